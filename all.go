@@ -0,0 +1,133 @@
+package bongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// DefaultMaxResults is how many documents ResultSet.All loads unless Connection.MaxResults is set
+const DefaultMaxResults = 10000
+
+// ResultLimitError is returned by ResultSet.All when there are more results than the connection's
+// MaxResults. Paginate the result set, or iterate it with Next, to load more
+type ResultLimitError struct {
+	Max int
+}
+
+func (e *ResultLimitError) Error() string {
+	return fmt.Sprintf("More than %d results", e.Max)
+}
+
+// Is ...
+func (e *ResultLimitError) Is(target error) bool {
+	return target == ErrResultLimit
+}
+
+var trackableType = reflect.TypeOf((*Trackable)(nil)).Elem()
+
+// maxResults returns the limit for All, or 0 for none
+func (m *Connection) maxResults() int {
+	switch {
+	case m.MaxResults < 0:
+		return 0
+	case m.MaxResults == 0:
+		return DefaultMaxResults
+	}
+	return m.MaxResults
+}
+
+// All loads every result into result, which must be a pointer to a slice of documents or of
+// pointers to documents. Like Next, it runs the AfterFind hooks, marks the documents as not
+// new and resets the DiffTracker of Trackable documents. The result set is freed afterwards.
+// result is only set if every document could be loaded. Trackable documents must be loaded into a
+// slice of pointers, since their DiffTracker keeps a pointer to the document it tracks
+func (r *ResultSet) All(result interface{}) error {
+	defer r.Free()
+
+	slice := reflect.ValueOf(result)
+	if slice.Kind() != reflect.Ptr || slice.Elem().Kind() != reflect.Slice {
+		return errors.New("result must be a pointer to a slice")
+	}
+
+	elemType := slice.Elem().Type().Elem()
+	isPtr := elemType.Kind() == reflect.Ptr
+	if isPtr {
+		elemType = elemType.Elem()
+	} else if reflect.PtrTo(elemType).Implements(trackableType) {
+		// The trackers would track the values that were copied into the slice
+		return fmt.Errorf("%s is Trackable, load it into a slice of pointers", elemType)
+	}
+
+	max := r.Collection.Connection.maxResults()
+	docs := reflect.MakeSlice(slice.Elem().Type(), 0, 0)
+
+	for {
+		// Look for one more result without decoding it, so no hook runs past the limit
+		if max > 0 && docs.Len() == max {
+			if r.moreResults() {
+				return &ResultLimitError{max}
+			}
+			break
+		}
+
+		doc := reflect.New(elemType)
+		if !r.Next(doc.Interface()) {
+			break
+		}
+
+		if isPtr {
+			docs = reflect.Append(docs, doc)
+		} else {
+			docs = reflect.Append(docs, doc.Elem())
+		}
+	}
+
+	if r.Error != nil {
+		return r.Error
+	}
+
+	slice.Elem().Set(docs)
+	return nil
+}
+
+// moreResults reports whether Next would find another result, once it has been called
+func (r *ResultSet) moreResults() bool {
+	if r.paged {
+		return len(r.page) > 0
+	}
+	if !r.loadedIter {
+		return false
+	}
+
+	if r.Iter.Next(&bson.Raw{}) {
+		return true
+	}
+	if err := r.Iter.Err(); err != nil {
+		r.Error = wrapError(err)
+	}
+	return false
+}
+
+// Collect loads every result of the result set into a slice of T, which can be a document
+// struct or a pointer to one. See ResultSet.All
+func Collect[T any](r *ResultSet) ([]T, error) {
+	docs := []T{}
+	if err := r.All(&docs); err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+// FindAll finds every document matching the query into a slice of T, see Collect
+func FindAll[T any](c *Collection, query interface{}, opts ...FindOption) ([]T, error) {
+	return Collect[T](c.Find(query, opts...))
+}
+
+// FindAllCtx is FindAll with a context, see FindCtx
+func FindAllCtx[T any](ctx context.Context, c *Collection, query interface{}, opts ...FindOption) ([]T, error) {
+	return Collect[T](c.FindCtx(ctx, query, opts...))
+}
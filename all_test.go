@@ -0,0 +1,111 @@
+package bongo
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// Counts the AfterFind hooks run on countedDocuments
+var countedFinds int
+
+type countedDocument struct {
+	DocumentBase `bson:",inline"`
+}
+
+func (c *countedDocument) AfterFind(*Collection) error {
+	countedFinds++
+	return nil
+}
+
+func TestAll(t *testing.T) {
+	conn := getConnection()
+	collection := conn.Collection("tests")
+	defer conn.Session.Close()
+
+	Convey("All", t, func() {
+		for i := 0; i < 3; i++ {
+			collection.Save(&hookedDocument{})
+		}
+
+		Convey("should load into a slice of documents and run the hooks", func() {
+			docs := []hookedDocument{}
+			err := collection.Find(nil).All(&docs)
+			So(err, ShouldEqual, nil)
+			So(len(docs), ShouldEqual, 3)
+
+			for _, doc := range docs {
+				So(doc.RanAfterFind, ShouldEqual, true)
+				So(doc.IsNew(), ShouldEqual, false)
+			}
+		})
+
+		Convey("should load into a slice of pointers with generics", func() {
+			docs, err := FindAll[*hookedDocument](collection, nil)
+			So(err, ShouldEqual, nil)
+			So(len(docs), ShouldEqual, 3)
+			So(docs[0].RanAfterFind, ShouldEqual, true)
+
+			rset := collection.Find(nil)
+			rset.Paginate(2, 2)
+			docs, err = Collect[*hookedDocument](rset)
+			So(err, ShouldEqual, nil)
+			So(len(docs), ShouldEqual, 1)
+		})
+
+		Convey("should reset the diff tracker of trackable documents", func() {
+			collection.Save(&trackedDocument{Name: "foo"})
+
+			docs, err := FindAll[*trackedDocument](collection, bson.M{"name": "foo"})
+			So(err, ShouldEqual, nil)
+			So(len(docs), ShouldEqual, 1)
+
+			isNew, changed, err := docs[0].GetDiffTracker().Compare(true)
+			So(err, ShouldEqual, nil)
+			So(isNew, ShouldEqual, false)
+			So(changed, ShouldBeEmpty)
+		})
+
+		Convey("should refuse to load more than the max results", func() {
+			conn.MaxResults = 2
+			defer func() { conn.MaxResults = 0 }()
+
+			docs := []*hookedDocument{}
+			err := collection.Find(nil).All(&docs)
+			So(errors.Is(err, ErrResultLimit), ShouldEqual, true)
+			So(docs, ShouldBeEmpty)
+
+			conn.MaxResults = -1
+			So(collection.Find(nil).All(&docs), ShouldEqual, nil)
+			So(len(docs), ShouldEqual, 3)
+		})
+
+		Convey("should not load the document past the max results", func() {
+			conn.MaxResults = 2
+			defer func() { conn.MaxResults = 0 }()
+
+			countedFinds = 0
+			err := collection.Find(nil).All(&[]*countedDocument{})
+			So(errors.Is(err, ErrResultLimit), ShouldEqual, true)
+			So(countedFinds, ShouldEqual, 2)
+		})
+
+		Convey("should refuse a slice of Trackable values", func() {
+			err := collection.Find(nil).All(&[]trackedDocument{})
+			So(err, ShouldNotBeNil)
+
+			_, err = FindAll[trackedDocument](collection, nil)
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("should only take a pointer to a slice", func() {
+			So(collection.Find(nil).All([]hookedDocument{}), ShouldNotBeNil)
+		})
+
+		Reset(func() {
+			conn.Session.DB("bongotest").DropDatabase()
+		})
+	})
+}
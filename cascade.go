@@ -1,11 +1,14 @@
 package bongo
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"github.com/maxwellhealth/go-dotaccess"
 	"github.com/oleiade/reflections"
 	"gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
+	"reflect"
 	"strings"
 )
 
@@ -15,6 +18,16 @@ const (
 	RelOne  = iota
 )
 
+// Delete policies (what happens to related documents when a document is deleted)
+const (
+	// Remove the cascaded data from the related documents
+	OnDeleteNullify = iota
+	// Refuse to delete the document while related documents exist
+	OnDeleteRestrict = iota
+	// Delete the related documents too, with Collection.Delete so their hooks and cascades run
+	OnDeleteCascade = iota
+)
+
 // ReferenceField ...
 type ReferenceField struct {
 	BsonName string
@@ -52,176 +65,476 @@ type CascadeConfig struct {
 	// If this is provided, use this field instead of _id for determining "sameness".
 	// This must also be a bson.ObjectId field
 	ReferenceQuery []*ReferenceField
+	// For RelMany, keep the array sorted by this field of the cascaded data ("-field" for descending)
+	SortKey string
+	// For RelMany, the maximum length of the array. When a push makes it longer, the first MaxLength
+	// entries are kept if there is a SortKey, otherwise the newest MaxLength
+	MaxLength int
+	// What to do with the related documents on delete: OnDeleteNullify (the default), OnDeleteRestrict
+	// or OnDeleteCascade. OnDeleteCascade loads them into new instances of Instance's type
+	OnDelete int
 }
 
 // CascadeFilter ...
 type CascadeFilter func(data map[string]interface{})
 
-// CascadeSave cascades a document's properties to related documents,
-// after it has been prepared for db insertion (encrypted, etc)
-func CascadeSave(collection *Collection, doc Document) error {
-	// Find out which properties to cascade
-	if conv, ok := doc.(CascadingDocument); ok {
-		toCascade := conv.GetCascade(collection)
-		for _, conf := range toCascade {
+// CascadeFailure describes a single CascadeConfig that could not be applied
+type CascadeFailure struct {
+	// The config that failed
+	Config *CascadeConfig
+	// Name of the collection the config cascades to
+	Collection string
+	// The underlying error (usually from mgo)
+	Err error
+}
 
-			if len(conf.ReferenceQuery) == 0 {
-				conf.ReferenceQuery = []*ReferenceField{&ReferenceField{"_id", doc.GetID()}}
-			}
-			_, err := cascadeSaveWithConfig(conf, doc)
-			if err != nil {
-				return err
-			}
-			if conf.Nest {
-				results := conf.Collection.Find(conf.Query)
+// CascadeError collects every failed CascadeConfig of a synchronous cascade
+type CascadeError struct {
+	Failures []*CascadeFailure
+}
 
-				for results.Next(conf.Instance) {
-					err = CascadeSave(conf.Collection, conf.Instance)
-					if err != nil {
-						return err
-					}
-				}
+func (c *CascadeError) Error() string {
+	errs := make([]string, len(c.Failures))
 
-			}
+	for i, f := range c.Failures {
+		errs[i] = f.Collection + ": " + f.Err.Error()
+	}
+	return "Cascade failed. (" + strings.Join(errs, ", ") + ")"
+}
+
+// Is ...
+func (c *CascadeError) Is(target error) bool {
+	return target == ErrCascade
+}
+
+// Unwrap returns the underlying error of every failure
+func (c *CascadeError) Unwrap() []error {
+	errs := make([]error, len(c.Failures))
+
+	for i, f := range c.Failures {
+		errs[i] = f.Err
+	}
+	return errs
+}
+
+// add records a failure for the config. Failures from nested cascades are merged as they are
+func (c *CascadeError) add(conf *CascadeConfig, err error) {
+	if nested, ok := err.(*CascadeError); ok {
+		c.Failures = append(c.Failures, nested.Failures...)
+		return
+	}
+
+	name := ""
+	if conf.Collection != nil {
+		name = conf.Collection.Name
+	}
+
+	c.Failures = append(c.Failures, &CascadeFailure{
+		Config:     conf,
+		Collection: name,
+		Err:        wrapError(err),
+	})
+}
+
+// errOrNil returns nil if nothing failed, so the result can be returned as an error
+func (c *CascadeError) errOrNil() error {
+	if len(c.Failures) > 0 {
+		return c
+	}
+	return nil
+}
+
+// DeleteRestrictedError is returned by Delete if related documents exist for a config with OnDeleteRestrict
+type DeleteRestrictedError struct {
+	// The collection of the related documents
+	Collection string
+	Count      int
+}
+
+func (d *DeleteRestrictedError) Error() string {
+	return fmt.Sprintf("Delete restricted: %d related documents in %s", d.Count, d.Collection)
+}
+
+// Is ...
+func (d *DeleteRestrictedError) Is(target error) bool {
+	return target == ErrDeleteRestricted
+}
+
+// checkDeleteRestrictions returns a *DeleteRestrictedError if related documents exist for a
+// config of the document with OnDeleteRestrict
+func checkDeleteRestrictions(collection *Collection, doc Document) (err error) {
+	defer recoverCascade(collection, CascadeOpDelete, doc, &err)
+
+	configs, err := cascadeConfigs(collection, doc)
+	if err != nil {
+		return err
+	}
+
+	for _, conf := range configs {
+		if conf.OnDelete != OnDeleteRestrict {
+			continue
+		}
+
+		count, err := conf.Collection.Find(conf.Query).Query.Count()
+		if err != nil {
+			return wrapError(err)
+		}
+		if count > 0 {
+			return &DeleteRestrictedError{Collection: conf.Collection.Name, Count: count}
 		}
 	}
 	return nil
 }
 
-// CascadeDelete deletes references to a document from its related documents
-func CascadeDelete(collection *Collection, doc interface{}) {
+// CascadeSave cascades a document's properties to related documents,
+// after it has been prepared for db insertion (encrypted, etc).
+// Every config is attempted; failures are returned together as a *CascadeError
+func CascadeSave(collection *Collection, doc Document) error {
+	return cascadeSaveCtx(context.Background(), collection, doc, nil)
+}
+
+// cascadeSaveCtx runs the cascades of the document. toCascade are built from the document if nil
+func cascadeSaveCtx(ctx context.Context, collection *Collection, doc Document, toCascade []*CascadeConfig) (err error) {
+	defer recoverCascade(collection, CascadeOpSave, doc, &err)
+
+	ctx, err = enterCascade(ctx, collection, doc.GetID())
+	if err != nil {
+		return err
+	}
+
+	cErr := &CascadeError{}
+
 	// Find out which properties to cascade
-	if conv, ok := doc.(interface {
-		GetCascade(*Collection) []*CascadeConfig
-	}); ok {
-		toCascade := conv.GetCascade(collection)
-
-		// Get the ID
-
-		for _, conf := range toCascade {
-			if len(conf.ReferenceQuery) == 0 {
-				id, err := reflections.GetField(doc, "ID")
-				if err != nil {
-					panic(err)
-				}
-				conf.ReferenceQuery = []*ReferenceField{&ReferenceField{"_id", id}}
+	if toCascade == nil {
+		toCascade, err = cascadeConfigs(collection, doc)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, conf := range toCascade {
+		if len(conf.ReferenceQuery) == 0 {
+			conf.ReferenceQuery = []*ReferenceField{&ReferenceField{"_id", doc.GetID()}}
+		}
+
+		conf := conf
+		err := observeCascade(collection, CascadeOpSave, doc.GetID(), conf, func() (*mgo.ChangeInfo, error) {
+			info, err := cascadeSaveWithConfig(conf, doc)
+			if err != nil || !conf.Nest {
+				return info, err
 			}
+			return info, nestCascade(ctx, conf)
+		})
+
+		if err != nil {
+			cErr.add(conf, err)
+		}
+	}
+	return cErr.errOrNil()
+}
 
-			cascadeDeleteWithConfig(conf)
+// buildCascadeConfigs is cascadeConfigs for a cascade that runs later, recovering panics like the cascade would
+func buildCascadeConfigs(collection *Collection, doc Document) (toCascade []*CascadeConfig, err error) {
+	defer recoverCascade(collection, CascadeOpSave, doc, &err)
+	return cascadeConfigs(collection, doc)
+}
+
+// nestCascade runs the cascades of the related documents of the config
+func nestCascade(ctx context.Context, conf *CascadeConfig) error {
+	cErr := &CascadeError{}
+	results := conf.Collection.FindCtx(ctx, conf.Query)
 
+	for results.Next(conf.Instance) {
+		nested, ok, err := descendCascade(ctx, conf.Collection, conf.Instance.GetID())
+		if err != nil {
+			cErr.add(conf, err)
+			break
+		}
+		// Already cascaded further up the chain
+		if !ok {
+			continue
 		}
 
+		err = cascadeSaveCtx(nested, conf.Collection, conf.Instance, nil)
+		if err != nil {
+			cErr.add(conf, err)
+		}
 	}
+
+	if results.Error != nil {
+		cErr.add(conf, results.Error)
+	}
+	results.Free()
+
+	return cErr.errOrNil()
 }
 
-// Runs a cascaded delete operation with one configuration
-func cascadeDeleteWithConfig(conf *CascadeConfig) (*mgo.ChangeInfo, error) {
+// CascadeDelete deletes references to a document from its related documents, or deletes the
+// related documents for configs with OnDeleteCascade.
+// Every config is attempted; failures are returned together as a *CascadeError
+func CascadeDelete(collection *Collection, doc interface{}) error {
+	return cascadeDeleteCtx(context.Background(), collection, doc)
+}
 
-	switch conf.RelType {
-	case RelOne:
-		update := map[string]map[string]interface{}{
-			"$set": map[string]interface{}{},
+func cascadeDeleteCtx(ctx context.Context, collection *Collection, doc interface{}) (err error) {
+	defer recoverCascade(collection, CascadeOpDelete, doc, &err)
+
+	if document, ok := doc.(Document); ok {
+		if ctx, err = enterCascade(ctx, collection, document.GetID()); err != nil {
+			return err
+		}
+	}
+
+	cErr := &CascadeError{}
+
+	// Find out which properties to cascade
+	toCascade, err := cascadeConfigs(collection, doc)
+	if err != nil {
+		return err
+	}
+
+	// Get the ID
+	id, idErr := reflections.GetField(doc, "ID")
+
+	for _, conf := range toCascade {
+		if conf.OnDelete == OnDeleteRestrict {
+			// Checked before the document was deleted
+			continue
 		}
 
-		if len(conf.ThroughProp) > 0 {
-			update["$set"][conf.ThroughProp] = nil
-		} else {
-			for _, p := range conf.Properties {
-				update["$set"][p] = nil
+		if len(conf.ReferenceQuery) == 0 {
+			if idErr != nil {
+				cErr.add(conf, idErr)
+				continue
 			}
+			conf.ReferenceQuery = []*ReferenceField{&ReferenceField{"_id", id}}
 		}
 
-		return conf.Collection.Collection().UpdateAll(conf.Query, update)
-	case RelMany:
-		update := map[string]map[string]interface{}{
-			"$pull": map[string]interface{}{},
+		conf := conf
+		err := observeCascade(collection, CascadeOpDelete, id, conf, func() (*mgo.ChangeInfo, error) {
+			if conf.OnDelete == OnDeleteCascade {
+				return nil, deleteRelated(ctx, conf)
+			}
+			return cascadeDeleteWithConfig(conf)
+		})
+
+		if err != nil {
+			cErr.add(conf, err)
+		}
+	}
+	return cErr.errOrNil()
+}
+
+// deleteRelated deletes the related documents of the config, skipping documents that were
+// already deleted further up the chain of cascades
+func deleteRelated(ctx context.Context, conf *CascadeConfig) error {
+	if conf.Instance == nil {
+		return errors.New("OnDeleteCascade needs an Instance to load the related documents into")
+	}
+
+	docType := reflect.TypeOf(conf.Instance).Elem()
+
+	// Load them all first, deleting while iterating could skip documents
+	related := []Document{}
+	results := conf.Collection.FindCtx(ctx, conf.Query)
+
+	for {
+		doc := reflect.New(docType).Interface().(Document)
+		if !results.Next(doc) {
+			break
+		}
+		related = append(related, doc)
+	}
+	results.Free()
+
+	if results.Error != nil {
+		return results.Error
+	}
+
+	cErr := &CascadeError{}
+	for _, doc := range related {
+		nested, ok, err := descendCascade(ctx, conf.Collection, doc.GetID())
+		if err != nil {
+			cErr.add(conf, err)
+			break
+		}
+		// Already deleted further up the chain
+		if !ok {
+			continue
 		}
 
-		q := bson.M{}
-		for _, f := range conf.ReferenceQuery {
-			q[f.BsonName] = f.Value
+		if err := conf.Collection.DeleteCtx(nested, doc); err != nil {
+			cErr.add(conf, err)
 		}
-		update["$pull"][conf.ThroughProp] = q
-		return conf.Collection.Collection().UpdateAll(conf.Query, update)
 	}
+	return cErr.errOrNil()
+}
 
-	return &mgo.ChangeInfo{}, errors.New("Invalid relation type")
+// Runs a cascaded delete operation with one configuration
+func cascadeDeleteWithConfig(conf *CascadeConfig) (*mgo.ChangeInfo, error) {
+	ops, err := planCascadeDelete(conf)
+	if err != nil {
+		return &mgo.ChangeInfo{}, err
+	}
+	return runCascadeOperations(conf, ops)
 }
 
 // Runs a cascaded save operation with one configuration
 func cascadeSaveWithConfig(conf *CascadeConfig, doc Document) (*mgo.ChangeInfo, error) {
-	// Create a new map with just the props to cascade
+	ops, err := planCascadeSave(conf)
+	if err != nil {
+		return &mgo.ChangeInfo{}, err
+	}
+	return runCascadeOperations(conf, ops)
+}
 
-	data := conf.Data
+// runCascadeOperations runs the updates in order, stopping at the first error
+func runCascadeOperations(conf *CascadeConfig, ops []*CascadeOperation) (*mgo.ChangeInfo, error) {
+	col := conf.Collection.Collection()
+	total := &mgo.ChangeInfo{}
 
-	switch conf.RelType {
-	case RelOne:
-		if len(conf.OldQuery) > 0 {
+	for _, op := range ops {
+		info, err := col.UpdateAll(op.Selector, op.Update)
+		if err != nil {
+			return total, err
+		}
+		total.Updated += info.Updated
+		total.Matched += info.Matched
+	}
+	return total, nil
+}
 
-			update1 := map[string]map[string]interface{}{
-				"$set": map[string]interface{}{},
-			}
+// nullify builds the update that removes the cascaded data of a RelOne config
+func nullify(conf *CascadeConfig) bson.M {
+	set := bson.M{}
 
-			if len(conf.ThroughProp) > 0 {
-				update1["$set"][conf.ThroughProp] = nil
-			} else {
-				for _, p := range conf.Properties {
-					update1["$set"][p] = nil
-				}
-			}
+	if len(conf.ThroughProp) > 0 {
+		set[conf.ThroughProp] = nil
+	} else {
+		for _, p := range conf.Properties {
+			set[p] = nil
+		}
+	}
+	return bson.M{"$set": set}
+}
+
+// referenceQuery matches the array entries of the config's document
+func referenceQuery(conf *CascadeConfig) bson.M {
+	q := bson.M{}
+	for _, f := range conf.ReferenceQuery {
+		q[f.BsonName] = f.Value
+	}
+	return q
+}
 
-			ret, err := conf.Collection.Collection().UpdateAll(conf.OldQuery, update1)
+// planCascadeDelete returns the updates that remove the cascaded data of the config
+func planCascadeDelete(conf *CascadeConfig) ([]*CascadeOperation, error) {
+	op := &CascadeOperation{
+		Collection:  conf.Collection.Name,
+		Kind:        "update",
+		Description: "remove from related documents",
+		Selector:    conf.Query,
+	}
+
+	switch conf.RelType {
+	case RelOne:
+		op.Update = nullify(conf)
+	case RelMany:
+		op.Update = bson.M{"$pull": bson.M{conf.ThroughProp: referenceQuery(conf)}}
+	default:
+		return nil, errors.New("Invalid relation type")
+	}
+	return []*CascadeOperation{op}, nil
+}
+
+// planCascadeSave returns the updates that cascade the data of the config. If there is an OldQuery,
+// the data is removed from the previous relations first (and only that, for RemoveOnly).
+// For RelMany the array entry is updated in place, and pushed to the related documents that don't
+// have it yet, so the entry is never missing and the order is kept
+func planCascadeSave(conf *CascadeConfig) ([]*CascadeOperation, error) {
+	ops := []*CascadeOperation{}
+	op := func(description string, selector, update bson.M) {
+		ops = append(ops, &CascadeOperation{
+			Collection:  conf.Collection.Name,
+			Kind:        "update",
+			Description: description,
+			Selector:    selector,
+			Update:      update,
+		})
+	}
 
+	switch conf.RelType {
+	case RelOne:
+		if len(conf.OldQuery) > 0 {
+			op("remove from previous relations", conf.OldQuery, nullify(conf))
 			if conf.RemoveOnly {
-				return ret, err
+				return ops, nil
 			}
 		}
 
-		update := make(map[string]interface{})
-
 		if len(conf.ThroughProp) > 0 {
-			m := bson.M{}
-			m[conf.ThroughProp] = data
-			update["$set"] = m
+			op("set on related documents", conf.Query, bson.M{"$set": bson.M{conf.ThroughProp: conf.Data}})
 		} else {
-			update["$set"] = data
+			op("set on related documents", conf.Query, bson.M{"$set": conf.Data})
 		}
-
-		// Just update
-		return conf.Collection.Collection().UpdateAll(conf.Query, update)
 	case RelMany:
-
-		update1 := map[string]map[string]interface{}{
-			"$pull": map[string]interface{}{},
-		}
-
-		q := bson.M{}
-		for _, f := range conf.ReferenceQuery {
-			q[f.BsonName] = f.Value
-		}
-		update1["$pull"][conf.ThroughProp] = q
+		ref := referenceQuery(conf)
 
 		if len(conf.OldQuery) > 0 {
-			ret, err := conf.Collection.Collection().UpdateAll(conf.OldQuery, update1)
+			op("remove from previous relations", conf.OldQuery, bson.M{"$pull": bson.M{conf.ThroughProp: ref}})
 			if conf.RemoveOnly {
-				return ret, err
+				return ops, nil
 			}
 		}
 
-		// Remove self from current relations, so we can replace it
-		conf.Collection.Collection().UpdateAll(conf.Query, update1)
+		elem := bson.M{"$elemMatch": ref}
+		has := andQuery(conf.Query, bson.M{conf.ThroughProp: elem})
+
+		op("update entry in place", has, bson.M{"$set": bson.M{conf.ThroughProp + ".$": conf.Data}})
 
-		update2 := map[string]map[string]interface{}{
-			"$push": map[string]interface{}{},
+		push := bson.M{"$each": []interface{}{conf.Data}}
+		if len(conf.SortKey) > 0 {
+			push["$sort"] = sortSpec(conf.SortKey)
+		}
+		if conf.MaxLength > 0 {
+			if len(conf.SortKey) > 0 {
+				push["$slice"] = conf.MaxLength
+			} else {
+				// Keep the newest entries
+				push["$slice"] = -conf.MaxLength
+			}
 		}
 
-		update2["$push"][conf.ThroughProp] = data
-		return conf.Collection.Collection().UpdateAll(conf.Query, update2)
+		op("push where missing", andQuery(conf.Query, bson.M{conf.ThroughProp: bson.M{"$not": elem}}),
+			bson.M{"$push": bson.M{conf.ThroughProp: push}})
 
+		// The updated entry may have to move
+		if len(conf.SortKey) > 0 {
+			op("sort", has, bson.M{
+				"$push": bson.M{conf.ThroughProp: bson.M{"$each": []interface{}{}, "$sort": sortSpec(conf.SortKey)}},
+			})
+		}
+	default:
+		return nil, errors.New("Invalid relation type")
 	}
 
-	return &mgo.ChangeInfo{}, errors.New("Invalid relation type")
+	return ops, nil
+}
 
+// andQuery adds a condition to a query
+func andQuery(query bson.M, cond bson.M) bson.M {
+	if len(query) == 0 {
+		return cond
+	}
+	return bson.M{"$and": []interface{}{query, cond}}
+}
+
+// sortSpec turns "field" or "-field" into a $sort document
+func sortSpec(key string) bson.M {
+	if strings.HasPrefix(key, "-") {
+		return bson.M{key[1:]: -1}
+	}
+	return bson.M{key: 1}
 }
 
 // MapFromCascadeProperties if you need to, you can use this to construct the data map that
@@ -1,6 +1,7 @@
 package bongo
 
 import (
+	"errors"
 	. "github.com/smartystreets/goconvey/convey"
 	"gopkg.in/mgo.v2/bson"
 	"reflect"
@@ -133,6 +134,89 @@ func (c *SubChild) GetCascade(collection *Collection) []*CascadeConfig {
 	return []*CascadeConfig{cascadeSingle}
 }
 
+type BrokenCascade struct {
+	DocumentBase `bson:",inline"`
+	ParentID     bson.ObjectId
+}
+
+func (b *BrokenCascade) GetCascade(collection *Collection) []*CascadeConfig {
+	return []*CascadeConfig{
+		&CascadeConfig{
+			Collection: collection.Connection.Collection("parents"),
+			RelType:    -1,
+			Query: bson.M{
+				"_id": b.ParentID,
+			},
+		},
+	}
+}
+
+type deleteTeam struct {
+	DocumentBase    `bson:",inline"`
+	Name            string
+	onDelete        int
+	ranBeforeDelete bool
+}
+
+func (t *deleteTeam) BeforeDelete(c *Collection) error {
+	t.ranBeforeDelete = true
+	return nil
+}
+
+func (t *deleteTeam) GetCascade(collection *Collection) []*CascadeConfig {
+	return []*CascadeConfig{
+		&CascadeConfig{
+			Collection:  collection.Connection.Collection("roster"),
+			RelType:     RelOne,
+			ThroughProp: "team",
+			Data:        bson.M{"name": t.Name},
+			Query:       bson.M{"teamId": t.ID},
+			Instance:    &rosterEntry{},
+			OnDelete:    t.onDelete,
+		},
+	}
+}
+
+type rosterEntry struct {
+	DocumentBase `bson:",inline"`
+	TeamID       bson.ObjectId `bson:"teamId"`
+}
+
+// Cascades back to the team, so deleting a team with OnDeleteCascade makes a cycle
+func (r *rosterEntry) GetCascade(collection *Collection) []*CascadeConfig {
+	return []*CascadeConfig{
+		&CascadeConfig{
+			Collection:  collection.Connection.Collection("teams"),
+			RelType:     RelOne,
+			ThroughProp: "lastEntry",
+			Data:        bson.M{"_id": r.ID},
+			Query:       bson.M{"_id": r.TeamID},
+			Instance:    &deleteTeam{},
+			OnDelete:    OnDeleteCascade,
+		},
+	}
+}
+
+type rankedChild struct {
+	DocumentBase `bson:",inline"`
+	ParentID     bson.ObjectId
+	Score        int
+}
+
+func (r *rankedChild) GetCascade(collection *Collection) []*CascadeConfig {
+	return []*CascadeConfig{
+		&CascadeConfig{
+			Collection:  collection.Connection.Collection("parents"),
+			RelType:     RelMany,
+			ThroughProp: "ranked",
+			Data:        bson.M{"_id": r.ID, "score": r.Score},
+			Query:       bson.M{"_id": r.ParentID},
+			SortKey:     "-score",
+			MaxLength:   2,
+		},
+	}
+}
+
 type SubChildRef struct {
 	ID  bson.ObjectId `bson:"_id,omitempty"`
 	Foo string
@@ -252,6 +336,188 @@ func TestCascade(t *testing.T) {
 
 	})
 
+	Convey("Cascade Save/Delete - synchronous", t, func() {
+		connection.Session.DB("bongotest").DropDatabase()
+		collection := connection.Collection("parents")
+		childCollection := connection.Collection("children")
+		childCollection.SyncCascade = true
+
+		parent := &Parent{
+			Bar: "Testy McGee",
+		}
+		err := collection.Save(parent)
+		So(err, ShouldEqual, nil)
+
+		Convey("should cascade before Save and Delete return", func() {
+			child := &Child{
+				ParentID: parent.ID,
+				Name:     "Foo McGoo",
+			}
+			err := childCollection.Save(child)
+			So(err, ShouldEqual, nil)
+
+			newParent := &Parent{}
+			collection.FindByID(parent.ID, newParent)
+			So(newParent.Child.Name, ShouldEqual, "Foo McGoo")
+			So(newParent.Child.ID.Hex(), ShouldEqual, child.ID.Hex())
+			So(len(newParent.Children), ShouldEqual, 1)
+
+			err = childCollection.Delete(child)
+			So(err, ShouldEqual, nil)
+
+			newParent2 := &Parent{}
+			collection.FindByID(parent.ID, newParent2)
+			So(newParent2.Child.Name, ShouldEqual, "")
+			So(len(newParent2.Children), ShouldEqual, 0)
+		})
+
+		Convey("should return a cascade error with every failed config", func() {
+			broken := &BrokenCascade{
+				ParentID: parent.ID,
+			}
+			err := childCollection.Save(broken)
+
+			cErr, ok := err.(*CascadeError)
+			So(ok, ShouldEqual, true)
+			So(len(cErr.Failures), ShouldEqual, 1)
+			So(cErr.Failures[0].Collection, ShouldEqual, "parents")
+			So(cErr.Failures[0].Err.Error(), ShouldEqual, "Invalid relation type")
+			So(broken.IsNew(), ShouldEqual, false)
+
+			err = childCollection.Delete(broken)
+			_, ok = err.(*CascadeError)
+			So(ok, ShouldEqual, true)
+		})
+	})
+
+	Convey("Cascade Save - RelMany updates", t, func() {
+		connection.Session.DB("bongotest").DropDatabase()
+		parents := connection.Collection("parents")
+		children := connection.Collection("children")
+		children.SyncCascade = true
+
+		parent := &Parent{}
+		So(parents.Save(parent), ShouldEqual, nil)
+
+		Convey("should update entries in place", func() {
+			first := &Child{ParentID: parent.ID, Name: "First"}
+			second := &Child{ParentID: parent.ID, Name: "Second"}
+			So(children.Save(first), ShouldEqual, nil)
+			So(children.Save(second), ShouldEqual, nil)
+
+			first.Name = "Renamed"
+			So(children.Save(first), ShouldEqual, nil)
+
+			saved := &Parent{}
+			parents.FindByID(parent.ID, saved)
+			So(len(saved.Children), ShouldEqual, 2)
+			So(saved.Children[0].Name, ShouldEqual, "Renamed")
+			So(saved.Children[1].Name, ShouldEqual, "Second")
+		})
+
+		Convey("should keep capped arrays sorted", func() {
+			rankedCollection := connection.Collection("ranked")
+			rankedCollection.SyncCascade = true
+
+			low := &rankedChild{ParentID: parent.ID, Score: 1}
+			for _, child := range []*rankedChild{low, {ParentID: parent.ID, Score: 5}, {ParentID: parent.ID, Score: 3}} {
+				So(rankedCollection.Save(child), ShouldEqual, nil)
+			}
+
+			scores := func() []interface{} {
+				saved := bson.M{}
+				parents.Collection().FindId(parent.ID).One(&saved)
+				res := []interface{}{}
+				for _, entry := range saved["ranked"].([]interface{}) {
+					res = append(res, entry.(bson.M)["score"])
+				}
+				return res
+			}
+			So(scores(), ShouldResemble, []interface{}{5, 3})
+
+			low.Score = 10
+			So(rankedCollection.Save(low), ShouldEqual, nil)
+			So(scores(), ShouldResemble, []interface{}{10, 5})
+		})
+	})
+
+	Convey("Cascade Delete - policies", t, func() {
+		connection.Session.DB("bongotest").DropDatabase()
+		connection.SyncCascade = true
+		defer func() {
+			connection.SyncCascade = false
+		}()
+
+		teams := connection.Collection("teams")
+		roster := connection.Collection("roster")
+
+		team := &deleteTeam{Name: "Bongos"}
+		So(teams.Save(team), ShouldEqual, nil)
+		for i := 0; i < 2; i++ {
+			So(roster.Save(&rosterEntry{TeamID: team.ID}), ShouldEqual, nil)
+		}
+
+		Convey("should nullify by default", func() {
+			So(teams.Save(team), ShouldEqual, nil)
+			count, _ := roster.Collection().Find(bson.M{"team.name": "Bongos"}).Count()
+			So(count, ShouldEqual, 2)
+
+			So(teams.Delete(team), ShouldEqual, nil)
+			count, _ = roster.Collection().Find(bson.M{"team": nil}).Count()
+			So(count, ShouldEqual, 2)
+		})
+
+		Convey("should refuse to delete with related documents if restricted", func() {
+			team.onDelete = OnDeleteRestrict
+			err := teams.Delete(team)
+
+			So(errors.Is(err, ErrDeleteRestricted), ShouldEqual, true)
+			var restricted *DeleteRestrictedError
+			So(errors.As(err, &restricted), ShouldEqual, true)
+			So(restricted.Collection, ShouldEqual, "roster")
+			So(restricted.Count, ShouldEqual, 2)
+			So(team.ranBeforeDelete, ShouldEqual, false)
+
+			So(teams.FindByID(team.ID, &deleteTeam{}), ShouldEqual, nil)
+		})
+
+		Convey("should run nested cascades in the mode of the parent cascade", func() {
+			connection.SyncCascade = false
+			observer := &recordingObserver{}
+			connection.CascadeObserver = observer
+			defer func() {
+				connection.CascadeObserver = nil
+			}()
+
+			// Only the top collection is synchronous, the roster entries' own cascades still run
+			// before Delete returns
+			teams := connection.Collection("teams")
+			teams.SyncCascade = true
+			team.onDelete = OnDeleteCascade
+			So(teams.Delete(team), ShouldEqual, nil)
+
+			observer.mutex.Lock()
+			defer observer.mutex.Unlock()
+			fromRoster := 0
+			for _, event := range observer.finished {
+				if event.Collection == "roster" {
+					fromRoster++
+				}
+			}
+			So(fromRoster, ShouldEqual, 2)
+		})
+
+		Convey("should delete related documents, stopping at cycles", func() {
+			team.onDelete = OnDeleteCascade
+			So(teams.Delete(team), ShouldEqual, nil)
+
+			count, _ := roster.Collection().Count()
+			So(count, ShouldEqual, 0)
+			count, _ = teams.Collection().Count()
+			So(count, ShouldEqual, 0)
+		})
+	})
+
 	Convey("MapFromCascadeProperties", t, func() {
 		parent := &Parent{
 			Bar: "bar",
@@ -0,0 +1,544 @@
+package bongo
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// Cascade issue types
+const (
+	// A related document doesn't have the cascaded data
+	CascadeMissing = iota
+	// A related document has cascaded data that differs from the source document
+	CascadeStale = iota
+	// A related document has cascaded data of a source document that doesn't cascade to it (anymore)
+	CascadeOrphaned = iota
+)
+
+// CascadeIssue is a related document whose cascaded data doesn't match its source
+type CascadeIssue struct {
+	Type int
+	// The source document. Empty for orphans
+	Source bson.ObjectId
+	// The related document
+	Collection string
+	Target     interface{}
+	// The property holding the cascaded data. Empty if it is cascaded to the root of the document
+	ThroughProp string
+	// The cascaded data as it should be (nil for orphans) and as it is (nil if missing)
+	Expected interface{}
+	Actual   interface{}
+}
+
+func (i *CascadeIssue) String() string {
+	desc := [...]string{"missing", "stale", "orphaned"}[i.Type]
+	target := fmt.Sprintf("%s %v", i.Collection, i.Target)
+	if len(i.ThroughProp) > 0 {
+		target += "." + i.ThroughProp
+	}
+
+	if i.Type == CascadeOrphaned {
+		return fmt.Sprintf("%s: orphaned", target)
+	}
+	return fmt.Sprintf("%s: %s (from %s)", target, desc, i.Source.Hex())
+}
+
+// CascadeProgress is reported after every batch
+type CascadeProgress struct {
+	// Source documents checked so far, out of Total
+	Processed int
+	Total     int
+	Issues    int
+	Repaired  int
+}
+
+// CascadeReport is the result of VerifyCascades or RepairCascades
+type CascadeReport struct {
+	Collection string
+	Processed  int
+	Issues     []*CascadeIssue
+	// Number of issues that have been fixed (RepairCascades only)
+	Repaired int
+}
+
+type cascadeCheckOptions struct {
+	batchSize int
+	progress  func(CascadeProgress)
+}
+
+// CascadeCheckOption configures VerifyCascades and RepairCascades
+type CascadeCheckOption func(*cascadeCheckOptions)
+
+// CascadeBatchSize sets how many source documents are checked (and repaired) at a time. Defaults to 100
+func CascadeBatchSize(n int) CascadeCheckOption {
+	return func(o *cascadeCheckOptions) {
+		if n > 0 {
+			o.batchSize = n
+		}
+	}
+}
+
+// CascadeProgressFunc is called after every batch
+func CascadeProgressFunc(fn func(CascadeProgress)) CascadeCheckOption {
+	return func(o *cascadeCheckOptions) {
+		o.progress = fn
+	}
+}
+
+// VerifyCascades loads every document of the collection into a new instance of prototype, recomputes
+// its cascade configs (see Relation and CascadingDocument) and reports related documents whose
+// cascaded data is missing, stale or orphaned
+func VerifyCascades(collection *Collection, prototype Document, opts ...CascadeCheckOption) (*CascadeReport, error) {
+	return checkCascades(collection, prototype, false, opts)
+}
+
+// RepairCascades is VerifyCascades, but rewrites the cascaded data of every issue found.
+// Missing and stale data is cascaded again, orphans are removed
+func RepairCascades(collection *Collection, prototype Document, opts ...CascadeCheckOption) (*CascadeReport, error) {
+	return checkCascades(collection, prototype, true, opts)
+}
+
+// A pending repair of a single config of a source document
+type cascadeRepair struct {
+	conf   *CascadeConfig
+	doc    Document
+	issues int
+	// RelMany arrays with duplicate entries are rewritten instead of updated in place
+	duplicates bool
+}
+
+// cascadeTarget is a (collection, ThroughProp) that source documents cascade to. Cascaded data
+// that isn't expected by any source document is orphaned
+type cascadeTarget struct {
+	collection  *Collection
+	throughProp string
+	relType     int
+	refFields   []string
+	// Target _id => reference keys of the expected data
+	expected map[interface{}]map[string]bool
+}
+
+// cascadeCheck is a config of a source document whose related documents are yet to be compared
+type cascadeCheck struct {
+	conf     *CascadeConfig
+	doc      Document
+	expected bson.M
+	ref      string
+}
+
+type cascadeChecker struct {
+	report   *CascadeReport
+	targets  map[string]*cascadeTarget
+	pending  []*cascadeCheck
+	repairs  []*cascadeRepair
+	repair   bool
+	progress CascadeProgress
+}
+
+func checkCascades(collection *Collection, prototype Document, repair bool, opts []CascadeCheckOption) (*CascadeReport, error) {
+	options := &cascadeCheckOptions{batchSize: 100}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	if !hasCascades(prototype) {
+		return nil, fmt.Errorf("%T has no relations and doesn't implement CascadingDocument", prototype)
+	}
+
+	checker := &cascadeChecker{
+		report:  &CascadeReport{Collection: collection.Name},
+		targets: make(map[string]*cascadeTarget),
+		repair:  repair,
+	}
+
+	// Count the query that's iterated, so soft deleted documents are left out of Total too
+	results := collection.Find(nil)
+	total, err := results.Query.Count()
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	checker.progress.Total = total
+
+	docType := reflect.TypeOf(prototype).Elem()
+	for {
+		doc := reflect.New(docType).Interface().(Document)
+		if !results.Next(doc) {
+			break
+		}
+
+		if err := checker.check(collection, doc); err != nil {
+			results.Free()
+			return checker.report, err
+		}
+
+		checker.report.Processed++
+		if checker.report.Processed%options.batchSize == 0 {
+			if err := checker.compare(); err != nil {
+				results.Free()
+				return checker.report, err
+			}
+			if err := checker.flush(options); err != nil {
+				results.Free()
+				return checker.report, err
+			}
+		}
+	}
+	results.Free()
+
+	if results.Error != nil {
+		return checker.report, results.Error
+	}
+
+	if err := checker.compare(); err != nil {
+		return checker.report, err
+	}
+	if err := checker.findOrphans(); err != nil {
+		return checker.report, err
+	}
+	return checker.report, checker.flush(options)
+}
+
+// check queues the cascade configs of the document, to be compared with the related documents by compare
+func (c *cascadeChecker) check(collection *Collection, doc Document) error {
+	configs, err := cascadeConfigs(collection, doc)
+	if err != nil {
+		return err
+	}
+
+	for _, conf := range configs {
+		if conf.RemoveOnly || conf.Collection == nil {
+			continue
+		}
+
+		if len(conf.ReferenceQuery) == 0 {
+			conf.ReferenceQuery = []*ReferenceField{&ReferenceField{"_id", doc.GetID()}}
+		}
+		// The previous relations are checked by findOrphans
+		conf.OldQuery = nil
+
+		expected, err := toBsonMap(conf.Data)
+		if err != nil {
+			return err
+		}
+		ref, err := referenceKey(conf.ReferenceQuery)
+		if err != nil {
+			return err
+		}
+
+		c.pending = append(c.pending, &cascadeCheck{conf: conf, doc: doc, expected: expected, ref: ref})
+	}
+	return nil
+}
+
+// compare loads the related documents of the pending configs and compares them with the data
+// cascaded to them. Configs that target related documents by _id (like declared relations do) are
+// loaded with one $in query per collection, others with a query each
+func (c *cascadeChecker) compare() error {
+	pending := c.pending
+	c.pending = nil
+
+	ids := map[*Collection][]interface{}{}
+	for _, check := range pending {
+		if id, ok := queriedID(check.conf.Query); ok {
+			ids[check.conf.Collection] = append(ids[check.conf.Collection], id)
+		}
+	}
+
+	byID := map[string]bson.M{}
+	for collection, in := range ids {
+		loaded := []bson.M{}
+		if err := collection.Collection().Find(bson.M{"_id": bson.M{"$in": in}}).All(&loaded); err != nil {
+			return wrapError(err)
+		}
+		for _, rel := range loaded {
+			byID[collection.Name+":"+valueKey(rel["_id"])] = rel
+		}
+	}
+
+	for _, check := range pending {
+		conf := check.conf
+
+		related := []bson.M{}
+		if id, ok := queriedID(conf.Query); ok {
+			if rel, found := byID[conf.Collection.Name+":"+valueKey(id)]; found {
+				related = append(related, rel)
+			}
+		} else if err := conf.Collection.Collection().Find(conf.Query).All(&related); err != nil {
+			return wrapError(err)
+		}
+
+		target := c.target(conf)
+		repair := &cascadeRepair{conf: conf, doc: check.doc}
+
+		for _, rel := range related {
+			id := rel["_id"]
+			if target.expected[id] == nil {
+				target.expected[id] = make(map[string]bool)
+			}
+			target.expected[id][check.ref] = true
+
+			issue := &CascadeIssue{
+				Source:      check.doc.GetID(),
+				Collection:  conf.Collection.Name,
+				Target:      id,
+				ThroughProp: conf.ThroughProp,
+				Expected:    check.expected,
+			}
+
+			issue.Type, issue.Actual = compareCascaded(conf, rel, check.expected)
+			if issue.Type >= 0 {
+				c.addIssue(issue)
+				repair.issues++
+
+				if matches, ok := issue.Actual.([]interface{}); ok && len(matches) > 1 {
+					repair.duplicates = true
+				}
+			}
+		}
+
+		if repair.issues > 0 {
+			c.repairs = append(c.repairs, repair)
+		}
+	}
+	return nil
+}
+
+// queriedID returns the _id a query matches, if that's all it matches on
+func queriedID(query interface{}) (interface{}, bool) {
+	m, ok := query.(bson.M)
+	if !ok || len(m) != 1 {
+		return nil, false
+	}
+
+	id, ok := m["_id"]
+	if _, isOperator := id.(bson.M); !ok || isOperator || id == nil {
+		return nil, false
+	}
+	return id, true
+}
+
+// valueKey identifies a value the same way whether it was loaded from the database or not
+func valueKey(value interface{}) string {
+	data, _ := bson.Marshal(bson.M{"v": value})
+	return string(data)
+}
+
+// compareCascaded returns the issue type (or -1 if the data is as expected) and the actual data
+func compareCascaded(conf *CascadeConfig, related bson.M, expected bson.M) (int, interface{}) {
+	if conf.RelType == RelMany {
+		arr, _ := lookupBsonPath(related, strings.Split(conf.ThroughProp, "."))
+		matches := []interface{}{}
+
+		if elems, ok := arr.([]interface{}); ok {
+			for _, elem := range elems {
+				if m, ok := elem.(bson.M); ok && matchesReference(m, conf.ReferenceQuery) {
+					matches = append(matches, m)
+				}
+			}
+		}
+
+		switch {
+		case len(matches) == 0:
+			return CascadeMissing, nil
+		case len(matches) > 1 || !reflect.DeepEqual(matches[0], expected):
+			return CascadeStale, matches
+		}
+		return -1, nil
+	}
+
+	if len(conf.ThroughProp) > 0 {
+		actual, ok := lookupBsonPath(related, strings.Split(conf.ThroughProp, "."))
+		switch {
+		case !ok || actual == nil:
+			return CascadeMissing, nil
+		case !reflect.DeepEqual(actual, expected):
+			return CascadeStale, actual
+		}
+		return -1, nil
+	}
+
+	// Cascaded to the root of the related document
+	actual := bson.M{}
+	for key := range expected {
+		if value, ok := related[key]; ok {
+			actual[key] = value
+		}
+	}
+
+	switch {
+	case len(actual) == 0 && len(expected) > 0:
+		return CascadeMissing, nil
+	case !reflect.DeepEqual(actual, expected):
+		return CascadeStale, actual
+	}
+	return -1, nil
+}
+
+func matchesReference(elem bson.M, refs []*ReferenceField) bool {
+	actual := []*ReferenceField{}
+	for _, ref := range refs {
+		value, _ := lookupBsonPath(elem, strings.Split(ref.BsonName, "."))
+		actual = append(actual, &ReferenceField{ref.BsonName, value})
+	}
+
+	actualKey, _ := referenceKey(actual)
+	expectedKey, _ := referenceKey(refs)
+	return actualKey == expectedKey
+}
+
+// referenceKey identifies the values of a reference query. The values are encoded as bson so
+// values loaded from the database compare equal to the values they were saved from
+func referenceKey(refs []*ReferenceField) (string, error) {
+	values := []interface{}{}
+	for _, ref := range refs {
+		values = append(values, ref.Value)
+	}
+
+	data, err := bson.Marshal(bson.M{"v": values})
+	return string(data), err
+}
+
+// target returns the cascade target of the config, registering it on first use
+func (c *cascadeChecker) target(conf *CascadeConfig) *cascadeTarget {
+	name := fmt.Sprintf("%s:%s:%d", conf.Collection.Name, conf.ThroughProp, conf.RelType)
+
+	if target, ok := c.targets[name]; ok {
+		return target
+	}
+
+	target := &cascadeTarget{
+		collection:  conf.Collection,
+		throughProp: conf.ThroughProp,
+		relType:     conf.RelType,
+		expected:    make(map[interface{}]map[string]bool),
+	}
+	for _, ref := range conf.ReferenceQuery {
+		target.refFields = append(target.refFields, ref.BsonName)
+	}
+
+	c.targets[name] = target
+	return target
+}
+
+// findOrphans scans every target for cascaded data that no source document expects.
+// Data cascaded to the root of related documents can't be told apart from their own, so it is skipped
+func (c *cascadeChecker) findOrphans() error {
+	for _, target := range c.targets {
+		if len(target.throughProp) == 0 {
+			continue
+		}
+
+		query := bson.M{target.throughProp: bson.M{"$ne": nil}}
+		if target.relType == RelMany {
+			query = bson.M{target.throughProp + ".0": bson.M{"$exists": true}}
+		}
+
+		iter := target.collection.Collection().Find(query).Iter()
+		related := bson.M{}
+
+		for iter.Next(&related) {
+			value, _ := lookupBsonPath(related, strings.Split(target.throughProp, "."))
+
+			elems := []interface{}{value}
+			if target.relType == RelMany {
+				elems, _ = value.([]interface{})
+			}
+
+			for _, elem := range elems {
+				m, ok := elem.(bson.M)
+				if !ok {
+					continue
+				}
+
+				refs := []*ReferenceField{}
+				referenced := false
+				for _, field := range target.refFields {
+					v, _ := lookupBsonPath(m, strings.Split(field, "."))
+					refs = append(refs, &ReferenceField{field, v})
+					referenced = referenced || v != nil
+				}
+
+				// Empty embedded structs don't reference anything
+				if !referenced {
+					continue
+				}
+
+				key, err := referenceKey(refs)
+				if err != nil {
+					iter.Close()
+					return err
+				}
+
+				if !target.expected[related["_id"]][key] {
+					c.addIssue(&CascadeIssue{
+						Type:        CascadeOrphaned,
+						Collection:  target.collection.Name,
+						Target:      related["_id"],
+						ThroughProp: target.throughProp,
+						Actual:      m,
+					})
+
+					if c.repair {
+						c.repairs = append(c.repairs, &cascadeRepair{
+							conf: &CascadeConfig{
+								Collection:     target.collection,
+								RelType:        target.relType,
+								ThroughProp:    target.throughProp,
+								Query:          bson.M{"_id": related["_id"]},
+								ReferenceQuery: refs,
+							},
+							issues: 1,
+						})
+					}
+				}
+			}
+			related = bson.M{}
+		}
+
+		if err := iter.Close(); err != nil {
+			return wrapError(err)
+		}
+	}
+	return nil
+}
+
+func (c *cascadeChecker) addIssue(issue *CascadeIssue) {
+	c.report.Issues = append(c.report.Issues, issue)
+	c.progress.Issues++
+}
+
+// flush runs the pending repairs and reports progress
+func (c *cascadeChecker) flush(options *cascadeCheckOptions) error {
+	if c.repair {
+		for _, repair := range c.repairs {
+			var err error
+			if repair.doc == nil {
+				// An orphan
+				_, err = cascadeDeleteWithConfig(repair.conf)
+			} else {
+				if repair.duplicates {
+					_, err = cascadeDeleteWithConfig(repair.conf)
+				}
+				if err == nil {
+					_, err = cascadeSaveWithConfig(repair.conf, repair.doc)
+				}
+			}
+
+			if err != nil {
+				return wrapError(err)
+			}
+			c.report.Repaired += repair.issues
+		}
+	}
+	c.repairs = nil
+
+	c.progress.Processed = c.report.Processed
+	c.progress.Repaired = c.report.Repaired
+	if options.progress != nil {
+		options.progress(c.progress)
+	}
+	return nil
+}
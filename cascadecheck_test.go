@@ -0,0 +1,96 @@
+package bongo
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"gopkg.in/mgo.v2/bson"
+	"testing"
+)
+
+func TestCascadeCheck(t *testing.T) {
+	connection := getConnection()
+	defer connection.Session.Close()
+
+	Convey("VerifyCascades/RepairCascades", t, func() {
+		connection.Session.DB("bongotest").DropDatabase()
+		parents := connection.Collection("parents")
+		children := connection.Collection("children")
+		children.SyncCascade = true
+
+		// A RelOne cascade keeps only one child per parent
+		parent := &Parent{Bar: "Parent"}
+		So(parents.Save(parent), ShouldEqual, nil)
+		other := &Parent{Bar: "Other"}
+		So(parents.Save(other), ShouldEqual, nil)
+
+		So(children.Save(&Child{ParentID: parent.ID, Name: "One"}), ShouldEqual, nil)
+		So(children.Save(&Child{ParentID: other.ID, Name: "Two"}), ShouldEqual, nil)
+
+		Convey("should find nothing if the cascaded data is current", func() {
+			report, err := VerifyCascades(children, &Child{})
+			So(err, ShouldEqual, nil)
+			So(report.Processed, ShouldEqual, 2)
+			So(len(report.Issues), ShouldEqual, 0)
+		})
+
+		Convey("should count only the documents it checks", func() {
+			children.SoftDelete = true
+			err := children.Collection().Update(bson.M{"name": "Two"}, bson.M{"$set": bson.M{"deletedAt": bson.Now()}})
+			So(err, ShouldEqual, nil)
+
+			progress := []CascadeProgress{}
+			report, err := VerifyCascades(children, &Child{}, CascadeProgressFunc(func(p CascadeProgress) {
+				progress = append(progress, p)
+			}))
+			So(err, ShouldEqual, nil)
+			So(report.Processed, ShouldEqual, 1)
+			So(progress[len(progress)-1].Total, ShouldEqual, 1)
+		})
+
+		Convey("should find and repair missing, stale and orphaned data", func() {
+			child := &Child{}
+			So(children.FindOne(bson.M{"name": "One"}, child), ShouldEqual, nil)
+
+			err := parents.Collection().UpdateId(parent.ID, bson.M{
+				"$pull": bson.M{"children": bson.M{"_id": child.ID}},
+			})
+			So(err, ShouldEqual, nil)
+			err = parents.Collection().UpdateId(parent.ID, bson.M{
+				"$push": bson.M{"children": bson.M{"_id": bson.NewObjectId(), "name": "Gone"}},
+			})
+			So(err, ShouldEqual, nil)
+			err = parents.Collection().UpdateId(parent.ID, bson.M{"$set": bson.M{"childProp": "Stale"}})
+			So(err, ShouldEqual, nil)
+
+			progress := []CascadeProgress{}
+			report, err := VerifyCascades(children, &Child{}, CascadeBatchSize(1), CascadeProgressFunc(func(p CascadeProgress) {
+				progress = append(progress, p)
+			}))
+			So(err, ShouldEqual, nil)
+
+			types := map[int]int{}
+			for _, issue := range report.Issues {
+				types[issue.Type]++
+			}
+			So(types[CascadeMissing], ShouldEqual, 1)
+			So(types[CascadeStale], ShouldEqual, 1)
+			So(types[CascadeOrphaned], ShouldEqual, 1)
+
+			So(len(progress), ShouldEqual, 3)
+			So(progress[2].Processed, ShouldEqual, 2)
+			So(progress[2].Total, ShouldEqual, 2)
+
+			report, err = RepairCascades(children, &Child{})
+			So(err, ShouldEqual, nil)
+			So(report.Repaired, ShouldEqual, 3)
+
+			report, err = VerifyCascades(children, &Child{})
+			So(err, ShouldEqual, nil)
+			So(len(report.Issues), ShouldEqual, 0)
+
+			newParent := &Parent{}
+			So(parents.FindByID(parent.ID, newParent), ShouldEqual, nil)
+			So(len(newParent.Children), ShouldEqual, 1)
+			So(newParent.Children[0].ID, ShouldEqual, child.ID)
+		})
+	})
+}
@@ -0,0 +1,151 @@
+package bongo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Defaults for Connection.CascadeMaxDepth and Connection.CascadeMaxDocuments
+const (
+	DefaultCascadeMaxDepth     = 10
+	DefaultCascadeMaxDocuments = 10000
+)
+
+// CascadeLimitError is returned when nested cascades (Nest or OnDeleteCascade) go deeper than
+// Connection.CascadeMaxDepth or touch more than Connection.CascadeMaxDocuments documents
+type CascadeLimitError struct {
+	// "depth" or "documents"
+	Limit string
+	Max   int
+	// The chain of documents that hit the limit, as "collection:id"
+	Path []string
+}
+
+func (c *CascadeLimitError) Error() string {
+	return fmt.Sprintf("Cascade %s limit of %d exceeded (%s)", c.Limit, c.Max, strings.Join(c.Path, " -> "))
+}
+
+// Is ...
+func (c *CascadeLimitError) Is(target error) bool {
+	return target == ErrCascadeLimit
+}
+
+// cascadePlan is shared by every step of a chain of cascades. It records the documents that have been
+// visited, so cycles end, and counts them against the limits
+type cascadePlan struct {
+	mutex        sync.Mutex
+	visited      map[string]bool
+	touched      int
+	maxDepth     int
+	maxDocuments int
+}
+
+// cascadeStep is a document in a chain of cascades. It is passed along in the context
+type cascadeStep struct {
+	plan       *cascadePlan
+	parent     *cascadeStep
+	collection string
+	id         interface{}
+	depth      int
+}
+
+type cascadeStepKey struct{}
+
+func newCascadePlan(conn *Connection) *cascadePlan {
+	plan := &cascadePlan{
+		visited:      make(map[string]bool),
+		maxDepth:     DefaultCascadeMaxDepth,
+		maxDocuments: DefaultCascadeMaxDocuments,
+	}
+
+	if conn != nil && conn.CascadeMaxDepth > 0 {
+		plan.maxDepth = conn.CascadeMaxDepth
+	}
+	if conn != nil && conn.CascadeMaxDocuments > 0 {
+		plan.maxDocuments = conn.CascadeMaxDocuments
+	}
+	return plan
+}
+
+// enterCascade makes the document the current step of the context's chain of cascades, starting a
+// new chain if there is none. Documents are entered even if they have been visited before, since
+// they are saved or deleted explicitly
+func enterCascade(ctx context.Context, collection *Collection, id interface{}) (context.Context, error) {
+	if step, ok := ctx.Value(cascadeStepKey{}).(*cascadeStep); ok {
+		// Already entered by descendCascade
+		if step.collection == collection.Name && step.id == id {
+			return ctx, nil
+		}
+	}
+
+	ctx, _, err := step(ctx, collection, id, true)
+	return ctx, err
+}
+
+// descendCascade adds the document as a step below the current one. It returns false if the document
+// has been visited before, and a *CascadeLimitError if the chain gets too deep or too big
+func descendCascade(ctx context.Context, collection *Collection, id interface{}) (context.Context, bool, error) {
+	return step(ctx, collection, id, false)
+}
+
+func step(ctx context.Context, collection *Collection, id interface{}, force bool) (context.Context, bool, error) {
+	next := &cascadeStep{collection: collection.Name, id: id}
+
+	if parent, ok := ctx.Value(cascadeStepKey{}).(*cascadeStep); ok {
+		next.plan = parent.plan
+		next.parent = parent
+		next.depth = parent.depth + 1
+	} else {
+		next.plan = newCascadePlan(collection.Connection)
+	}
+
+	plan := next.plan
+	key := fmt.Sprintf("%s:%v", collection.Name, id)
+
+	plan.mutex.Lock()
+	defer plan.mutex.Unlock()
+
+	if plan.visited[key] && !force {
+		return ctx, false, nil
+	}
+
+	if next.depth > plan.maxDepth {
+		return ctx, false, &CascadeLimitError{Limit: "depth", Max: plan.maxDepth, Path: next.path()}
+	}
+	if plan.touched >= plan.maxDocuments {
+		return ctx, false, &CascadeLimitError{Limit: "documents", Max: plan.maxDocuments, Path: next.path()}
+	}
+
+	plan.visited[key] = true
+	plan.touched++
+	return context.WithValue(ctx, cascadeStepKey{}, next), true, nil
+}
+
+// path lists the documents from the start of the chain to this step
+func (s *cascadeStep) path() []string {
+	path := []string{}
+	for step := s; step != nil; step = step.parent {
+		path = append([]string{fmt.Sprintf("%s:%v", step.collection, step.id)}, path...)
+	}
+	return path
+}
+
+// nestedCascade tells if the context's document was reached by a cascade. Its own cascades then
+// follow the mode of the one that reached it: inline if that was synchronous, and already in the
+// background otherwise. The SyncCascade of its collection doesn't matter
+func nestedCascade(ctx context.Context) bool {
+	step, ok := ctx.Value(cascadeStepKey{}).(*cascadeStep)
+	return ok && step.parent != nil
+}
+
+// detachCascade returns a background context with the chain of cascades of ctx, for cascades that
+// run in a goroutine and may outlive ctx
+func detachCascade(ctx context.Context) context.Context {
+	detached := context.Background()
+	if step, ok := ctx.Value(cascadeStepKey{}).(*cascadeStep); ok {
+		detached = context.WithValue(detached, cascadeStepKey{}, step)
+	}
+	return detached
+}
@@ -0,0 +1,100 @@
+package bongo
+
+import (
+	"errors"
+	. "github.com/smartystreets/goconvey/convey"
+	"gopkg.in/mgo.v2/bson"
+	"testing"
+)
+
+// cascadeNode cascades its name to its children, and nests into them
+type cascadeNode struct {
+	DocumentBase `bson:",inline"`
+	Name         string
+	ParentID     bson.ObjectId `bson:"parentId,omitempty"`
+}
+
+func (n *cascadeNode) GetCascade(collection *Collection) []*CascadeConfig {
+	return []*CascadeConfig{
+		&CascadeConfig{
+			Collection:  collection,
+			RelType:     RelOne,
+			ThroughProp: "parent",
+			Data:        bson.M{"_id": n.ID, "name": n.Name},
+			Query:       bson.M{"parentId": n.ID},
+			Nest:        true,
+			Instance:    &cascadeNode{},
+		},
+	}
+}
+
+func TestCascadePlan(t *testing.T) {
+	connection := getConnection()
+	defer connection.Session.Close()
+
+	Convey("Nested cascades", t, func() {
+		connection.Session.DB("bongotest").DropDatabase()
+		connection.CascadeMaxDepth = 0
+		connection.CascadeMaxDocuments = 0
+		nodes := connection.Collection("nodes")
+
+		// Saves the nodes without cascading
+		insert := func(docs ...*cascadeNode) {
+			for _, doc := range docs {
+				doc.ID = bson.NewObjectId()
+			}
+			for _, doc := range docs {
+				So(nodes.Collection().Insert(doc), ShouldEqual, nil)
+			}
+		}
+
+		Convey("should stop at cycles", func() {
+			a := &cascadeNode{Name: "a"}
+			b := &cascadeNode{Name: "b"}
+			insert(a, b)
+			nodes.Collection().UpdateId(a.ID, bson.M{"$set": bson.M{"parentId": b.ID}})
+			nodes.Collection().UpdateId(b.ID, bson.M{"$set": bson.M{"parentId": a.ID}})
+
+			So(CascadeSave(nodes, a), ShouldEqual, nil)
+
+			saved := bson.M{}
+			nodes.Collection().FindId(b.ID).One(&saved)
+			So(saved["parent"].(bson.M)["name"], ShouldEqual, "a")
+		})
+
+		Convey("should enforce the max depth", func() {
+			root := &cascadeNode{Name: "0"}
+			insert(root)
+			parent := root
+			for i := 0; i < 3; i++ {
+				child := &cascadeNode{Name: "child", ParentID: parent.ID}
+				insert(child)
+				parent = child
+			}
+
+			connection.CascadeMaxDepth = 2
+			err := CascadeSave(nodes, root)
+
+			So(errors.Is(err, ErrCascadeLimit), ShouldEqual, true)
+			var limit *CascadeLimitError
+			So(errors.As(err, &limit), ShouldEqual, true)
+			So(limit.Limit, ShouldEqual, "depth")
+			So(len(limit.Path), ShouldEqual, 4)
+		})
+
+		Convey("should enforce the max documents", func() {
+			root := &cascadeNode{Name: "0"}
+			insert(root)
+			for i := 0; i < 3; i++ {
+				insert(&cascadeNode{Name: "child", ParentID: root.ID})
+			}
+
+			connection.CascadeMaxDocuments = 3
+			err := CascadeSave(nodes, root)
+
+			var limit *CascadeLimitError
+			So(errors.As(err, &limit), ShouldEqual, true)
+			So(limit.Limit, ShouldEqual, "documents")
+		})
+	})
+}
@@ -0,0 +1,479 @@
+// Package cli implements the bongo command line tool.
+//
+// cmd/bongo is the generic build. To run your own migrations and use your models (to sync indexes
+// and verify or rebuild cascades), build your own binary:
+//
+//	func main() {
+//		app := &cli.App{
+//			Migrations: migrations.All,
+//			Models:     []interface{}{&models.Person{}, &models.Team{}},
+//		}
+//		app.Main()
+//	}
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/maxwellhealth/bongo"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const usage = `Usage: bongo [-url <mongodb url>] <command> [arguments]
+
+The URL defaults to $BONGO_URL, e.g. mongodb://localhost:27017/mydb
+
+Commands:
+  migrate [-dry-run] status|up|down [n]   Show, run or roll back (the last n) migrations
+  indexes [-drop] [-dry-run]              Sync the declared indexes of the models
+  cascade [-batch n] verify|rebuild <collection>
+                                          Check or repair the data cascaded from a collection
+  cascade explain [-delete] <collection> <id>
+                                          Show what saving (or deleting) a document would cascade
+  dump [-query <json>] <collection>       Write documents as extended JSON, one per line
+  load [-drop] <collection>               Insert (or replace by _id) documents read from dump
+  count [-query <json>] [-per-page n] [-page n] <collection>
+                                          Print pagination info for a query
+`
+
+// App is the command line tool
+type App struct {
+	// Migrations to run with "migrate"
+	Migrations []*bongo.Migration
+	// Models for "indexes" and "cascade". They must implement bongo.CollectionNamer
+	Models []interface{}
+	// Default to os.Stdin and os.Stdout
+	Stdin  io.Reader
+	Stdout io.Writer
+}
+
+// Main runs the app with the process' arguments, and exits with status 1 on errors
+func (a *App) Main() {
+	if err := a.Run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// Run runs the command in args (without the program name)
+func (a *App) Run(args []string) error {
+	if a.Stdin == nil {
+		a.Stdin = os.Stdin
+	}
+	if a.Stdout == nil {
+		a.Stdout = os.Stdout
+	}
+
+	flags := newFlagSet("bongo")
+	url := flags.String("url", os.Getenv("BONGO_URL"), "MongoDB URL")
+	if err := flags.Parse(args); err == flag.ErrHelp {
+		return errors.New(usage)
+	} else if err != nil {
+		return err
+	}
+
+	if flags.NArg() == 0 {
+		return errors.New(usage)
+	}
+	if len(*url) == 0 {
+		return errors.New("No MongoDB URL. Pass -url or set BONGO_URL")
+	}
+
+	cmd, args := flags.Arg(0), flags.Args()[1:]
+
+	// Fail before connecting rather than report an empty, normal looking result
+	switch {
+	case cmd == "migrate" && len(a.Migrations) == 0:
+		return errors.New("No migrations registered; build your own binary with cli.App")
+	case (cmd == "indexes" || cmd == "cascade") && len(a.Models) == 0:
+		return errors.New("No models registered; build your own binary with cli.App")
+	}
+
+	dialInfo, err := mgo.ParseURL(*url)
+	if err != nil {
+		return err
+	}
+
+	conn, err := bongo.Connect(dialInfo)
+	if err != nil {
+		return err
+	}
+	defer conn.Session.Close()
+
+	// Cascades are run by the command, there's nothing to wait for otherwise
+	conn.SyncCascade = true
+
+	switch cmd {
+	case "migrate":
+		return a.migrate(conn, args)
+	case "indexes":
+		return a.indexes(conn, args)
+	case "cascade":
+		return a.cascade(conn, args)
+	case "dump":
+		return a.dump(conn, args)
+	case "load":
+		return a.load(conn, args)
+	case "count":
+		return a.count(conn, args)
+	}
+	return fmt.Errorf("Unknown command %s\n\n%s", cmd, usage)
+}
+
+func newFlagSet(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	return flags
+}
+
+// collectionArg returns the only positional argument
+func collectionArg(flags *flag.FlagSet) (string, error) {
+	if flags.NArg() != 1 {
+		return "", fmt.Errorf("%s needs exactly one collection", flags.Name())
+	}
+	return flags.Arg(0), nil
+}
+
+func (a *App) printf(format string, args ...interface{}) {
+	fmt.Fprintf(a.Stdout, format, args...)
+}
+
+func (a *App) migrate(conn *bongo.Connection, args []string) error {
+	flags := newFlagSet("migrate")
+	dryRun := flags.Bool("dry-run", false, "")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	migrator := bongo.NewMigrator(conn)
+	migrator.DryRun = *dryRun
+	if err := migrator.Register(a.Migrations...); err != nil {
+		return err
+	}
+
+	verb := "Applied"
+	if *dryRun {
+		verb = "Would apply"
+	}
+
+	switch flags.Arg(0) {
+	case "", "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+
+		for _, status := range statuses {
+			state := "pending"
+			if status.Applied {
+				state = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+
+			description := "(not registered)"
+			if status.Migration != nil {
+				description = status.Migration.Description
+			}
+			a.printf("%-27s %s  %s\n", state, status.ID, description)
+		}
+		return nil
+	case "up":
+		applied, err := migrator.Up()
+		for _, migration := range applied {
+			a.printf("%s %s\n", verb, migration.ID)
+		}
+		return err
+	case "down":
+		n := 1
+		if flags.NArg() > 1 {
+			var err error
+			if n, err = strconv.Atoi(flags.Arg(1)); err != nil || n < 1 {
+				return fmt.Errorf("Invalid number of migrations %q", flags.Arg(1))
+			}
+		}
+
+		if *dryRun {
+			verb = "Would roll back"
+		} else {
+			verb = "Rolled back"
+		}
+
+		rolledBack, err := migrator.Down(n)
+		for _, migration := range rolledBack {
+			a.printf("%s %s\n", verb, migration.ID)
+		}
+		return err
+	}
+	return fmt.Errorf("Unknown migrate command %s", flags.Arg(0))
+}
+
+func (a *App) indexes(conn *bongo.Connection, args []string) error {
+	flags := newFlagSet("indexes")
+	drop := flags.Bool("drop", false, "")
+	dryRun := flags.Bool("dry-run", false, "")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	opts := []bongo.IndexOption{}
+	if *drop {
+		opts = append(opts, bongo.DropUndeclaredIndexes())
+	}
+	if *dryRun {
+		opts = append(opts, bongo.IndexDryRun())
+	}
+
+	reports, err := conn.EnsureIndexesWith(opts, a.Models...)
+	for _, report := range reports {
+		a.printf("%s: %d created, %d unchanged, %d drifted, %d undeclared, %d dropped\n",
+			report.Collection, len(report.Created), len(report.Unchanged), len(report.Drifted),
+			len(report.Undeclared), len(report.Dropped))
+
+		for _, index := range report.Created {
+			a.printf("  created    %s\n", indexName(index))
+		}
+		for _, drift := range report.Drifted {
+			a.printf("  drifted    %s\n", indexName(drift.Existing))
+		}
+		for _, index := range report.Undeclared {
+			a.printf("  undeclared %s\n", indexName(index))
+		}
+		for _, index := range report.Dropped {
+			a.printf("  dropped    %s\n", indexName(index))
+		}
+	}
+	return err
+}
+
+func indexName(index mgo.Index) string {
+	if len(index.Name) > 0 {
+		return index.Name
+	}
+	return strings.Join(index.Key, ",")
+}
+
+// model returns a new instance of the model stored in the collection
+func (a *App) model(collection string) (bongo.Document, error) {
+	for _, model := range a.Models {
+		if namer, ok := model.(bongo.CollectionNamer); ok && namer.CollectionName() == collection {
+			if doc, ok := reflect.New(reflect.TypeOf(model).Elem()).Interface().(bongo.Document); ok {
+				return doc, nil
+			}
+			return nil, fmt.Errorf("%T is not a bongo.Document", model)
+		}
+	}
+	return nil, fmt.Errorf("No model registered for collection %s", collection)
+}
+
+func (a *App) cascade(conn *bongo.Connection, args []string) error {
+	flags := newFlagSet("cascade")
+	batch := flags.Int("batch", 100, "")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.Arg(0) == "explain" {
+		return a.explainCascade(conn, flags.Args()[1:])
+	}
+
+	if flags.NArg() != 2 || (flags.Arg(0) != "verify" && flags.Arg(0) != "rebuild") {
+		return errors.New("Usage: cascade [-batch n] verify|rebuild <collection>")
+	}
+
+	name := flags.Arg(1)
+	prototype, err := a.model(name)
+	if err != nil {
+		return err
+	}
+
+	check := bongo.VerifyCascades
+	if flags.Arg(0) == "rebuild" {
+		check = bongo.RepairCascades
+	}
+
+	report, err := check(conn.Collection(name), prototype,
+		bongo.CascadeBatchSize(*batch),
+		bongo.CascadeProgressFunc(func(p bongo.CascadeProgress) {
+			a.printf("%d/%d documents, %d issues, %d repaired\n", p.Processed, p.Total, p.Issues, p.Repaired)
+		}))
+	if err != nil {
+		return err
+	}
+
+	for _, issue := range report.Issues {
+		a.printf("%s\n", issue)
+	}
+
+	if flags.Arg(0) == "verify" && len(report.Issues) > 0 {
+		return fmt.Errorf("%d cascade issues found", len(report.Issues))
+	}
+	return nil
+}
+
+func (a *App) explainCascade(conn *bongo.Connection, args []string) error {
+	flags := newFlagSet("cascade explain")
+	del := flags.Bool("delete", false, "")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 2 || !bson.IsObjectIdHex(flags.Arg(1)) {
+		return errors.New("Usage: cascade explain [-delete] <collection> <id>")
+	}
+
+	name := flags.Arg(0)
+	doc, err := a.model(name)
+	if err != nil {
+		return err
+	}
+
+	collection := conn.Collection(name)
+	if err := collection.FindByID(bson.ObjectIdHex(flags.Arg(1)), doc); err != nil {
+		return err
+	}
+
+	op := bongo.CascadeOpSave
+	if *del {
+		op = bongo.CascadeOpDelete
+	}
+
+	explanation, err := bongo.ExplainCascade(collection, doc, op)
+	if err != nil {
+		return err
+	}
+
+	out, err := json.MarshalIndent(explanation, "", "  ")
+	if err != nil {
+		return err
+	}
+	a.printf("%s\n", out)
+	return nil
+}
+
+func (a *App) dump(conn *bongo.Connection, args []string) error {
+	flags := newFlagSet("dump")
+	queryArg := flags.String("query", "", "")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	name, err := collectionArg(flags)
+	if err != nil {
+		return err
+	}
+	query, err := parseQuery(*queryArg)
+	if err != nil {
+		return err
+	}
+
+	iter := conn.Collection(name).Collection().Find(query).Iter()
+	defer iter.Close()
+
+	buf := &bytes.Buffer{}
+	doc := bson.D{}
+	for iter.Next(&doc) {
+		buf.Reset()
+		if err := marshalJSON(buf, doc); err != nil {
+			return err
+		}
+		buf.WriteByte('\n')
+
+		if _, err := a.Stdout.Write(buf.Bytes()); err != nil {
+			return err
+		}
+		doc = bson.D{}
+	}
+	return iter.Err()
+}
+
+func (a *App) load(conn *bongo.Connection, args []string) error {
+	flags := newFlagSet("load")
+	drop := flags.Bool("drop", false, "")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	name, err := collectionArg(flags)
+	if err != nil {
+		return err
+	}
+
+	col := conn.Collection(name).Collection()
+	if *drop {
+		if err := col.DropCollection(); err != nil && err.Error() != "ns not found" {
+			return err
+		}
+	}
+
+	dec := json.NewDecoder(a.Stdin)
+	dec.UseNumber()
+
+	count := 0
+	for {
+		value, err := unmarshalJSON(dec)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("Document %d: %s", count+1, err)
+		}
+
+		doc, ok := value.(bson.D)
+		if !ok {
+			return fmt.Errorf("Document %d: not a JSON object", count+1)
+		}
+
+		if id, ok := doc.Map()["_id"]; ok {
+			_, err = col.UpsertId(id, doc)
+		} else {
+			err = col.Insert(doc)
+		}
+		if err != nil {
+			return fmt.Errorf("Document %d: %s", count+1, err)
+		}
+		count++
+	}
+
+	a.printf("Loaded %d documents into %s\n", count, name)
+	return nil
+}
+
+func (a *App) count(conn *bongo.Connection, args []string) error {
+	flags := newFlagSet("count")
+	queryArg := flags.String("query", "", "")
+	perPage := flags.Int("per-page", 20, "")
+	page := flags.Int("page", 1, "")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	name, err := collectionArg(flags)
+	if err != nil {
+		return err
+	}
+	query, err := parseQuery(*queryArg)
+	if err != nil {
+		return err
+	}
+	if *perPage < 1 {
+		return errors.New("-per-page must be at least 1")
+	}
+
+	info, err := conn.Collection(name).Find(query).Paginate(*perPage, *page)
+	if err != nil {
+		return err
+	}
+
+	out, _ := json.MarshalIndent(info, "", "  ")
+	a.printf("%s\n", out)
+	return nil
+}
@@ -0,0 +1,29 @@
+package cli
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestRegistrations(t *testing.T) {
+	Convey("Commands without registrations", t, func() {
+		app := &App{}
+		url := "mongodb://localhost:27017/bongotest"
+
+		Convey("migrate should fail without migrations", func() {
+			err := app.Run([]string{"-url", url, "migrate", "status"})
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "No migrations registered")
+		})
+
+		Convey("indexes and cascade should fail without models", func() {
+			err := app.Run([]string{"-url", url, "indexes"})
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "No models registered")
+
+			err = app.Run([]string{"-url", url, "cascade", "verify", "players"})
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "No models registered")
+		})
+	})
+}
@@ -0,0 +1,229 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// Documents are dumped as MongoDB extended JSON, so types that JSON doesn't have (ObjectIds, dates,
+// 64 bit ints, binary data, regular expressions) survive a dump and load. Field order is kept
+
+// marshalJSON writes a value decoded from mgo (bson.D for documents) as extended JSON
+func marshalJSON(buf *bytes.Buffer, value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		buf.WriteString("null")
+	case bool:
+		buf.WriteString(strconv.FormatBool(v))
+	case int:
+		buf.WriteString(strconv.Itoa(v))
+	case int64:
+		return marshalJSON(buf, bson.D{{Name: "$numberLong", Value: strconv.FormatInt(v, 10)}})
+	case float64:
+		if math.IsInf(v, 0) || math.IsNaN(v) {
+			return fmt.Errorf("Can't dump %v as JSON", v)
+		}
+		s := strconv.FormatFloat(v, 'g', -1, 64)
+		// Make sure it's read back as a float
+		if !bytes.ContainsAny([]byte(s), ".eE") {
+			s += ".0"
+		}
+		buf.WriteString(s)
+	case string:
+		b, _ := json.Marshal(v)
+		buf.Write(b)
+	case bson.ObjectId:
+		return marshalJSON(buf, bson.D{{Name: "$oid", Value: v.Hex()}})
+	case time.Time:
+		return marshalJSON(buf, bson.D{{Name: "$date", Value: v.UTC().Format(time.RFC3339Nano)}})
+	case []byte:
+		return marshalJSON(buf, bson.Binary{Data: v})
+	case bson.Binary:
+		return marshalJSON(buf, bson.D{
+			{Name: "$binary", Value: base64.StdEncoding.EncodeToString(v.Data)},
+			{Name: "$type", Value: hex.EncodeToString([]byte{v.Kind})},
+		})
+	case bson.RegEx:
+		return marshalJSON(buf, bson.D{{Name: "$regex", Value: v.Pattern}, {Name: "$options", Value: v.Options}})
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, elem := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := marshalJSON(buf, elem); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case bson.D:
+		buf.WriteByte('{')
+		for i, elem := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := marshalJSON(buf, elem.Name); err != nil {
+				return err
+			}
+			buf.WriteByte(':')
+			if err := marshalJSON(buf, elem.Value); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	default:
+		return fmt.Errorf("Can't dump value of type %T", value)
+	}
+	return nil
+}
+
+// unmarshalJSON reads the next extended JSON value from the decoder (which must use UseNumber).
+// Documents are returned as bson.D. It returns io.EOF at the end of the input
+func unmarshalJSON(dec *json.Decoder) (interface{}, error) {
+	token, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := token.(type) {
+	case json.Delim:
+		switch t {
+		case '[':
+			arr := []interface{}{}
+			for dec.More() {
+				elem, err := unmarshalJSON(dec)
+				if err != nil {
+					return nil, unexpectedEOF(err)
+				}
+				arr = append(arr, elem)
+			}
+			_, err := dec.Token()
+			return arr, unexpectedEOF(err)
+		case '{':
+			doc := bson.D{}
+			for dec.More() {
+				key, err := dec.Token()
+				if err != nil {
+					return nil, unexpectedEOF(err)
+				}
+				value, err := unmarshalJSON(dec)
+				if err != nil {
+					return nil, unexpectedEOF(err)
+				}
+				doc = append(doc, bson.DocElem{Name: key.(string), Value: value})
+			}
+			if _, err := dec.Token(); err != nil {
+				return nil, unexpectedEOF(err)
+			}
+			return fromExtended(doc)
+		}
+		return nil, fmt.Errorf("Unexpected %s", t)
+	case json.Number:
+		return parseNumber(t)
+	}
+
+	// string, bool or nil
+	return token, nil
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// parseNumber keeps the int/float distinction. Ints that don't fit in 32 bits become int64
+func parseNumber(n json.Number) (interface{}, error) {
+	if i, err := strconv.ParseInt(string(n), 10, 64); err == nil {
+		if i < math.MinInt32 || i > math.MaxInt32 {
+			return i, nil
+		}
+		return int(i), nil
+	}
+	return n.Float64()
+}
+
+// fromExtended converts the extended JSON forms written by marshalJSON back to their types
+func fromExtended(doc bson.D) (interface{}, error) {
+	if len(doc) == 0 || len(doc) > 2 {
+		return doc, nil
+	}
+
+	m := doc.Map()
+	str := func(key string) (string, bool) {
+		s, ok := m[key].(string)
+		return s, ok
+	}
+
+	if len(doc) == 1 {
+		if s, ok := str("$oid"); ok {
+			if !bson.IsObjectIdHex(s) {
+				return nil, fmt.Errorf("Invalid $oid %q", s)
+			}
+			return bson.ObjectIdHex(s), nil
+		}
+		if s, ok := str("$date"); ok {
+			return time.Parse(time.RFC3339Nano, s)
+		}
+		if s, ok := str("$numberLong"); ok {
+			return strconv.ParseInt(s, 10, 64)
+		}
+		return doc, nil
+	}
+
+	if s, ok := str("$binary"); ok {
+		kind, ok := str("$type")
+		if !ok {
+			return doc, nil
+		}
+		data, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return nil, err
+		}
+		k, err := hex.DecodeString(kind)
+		if err != nil || len(k) != 1 {
+			return nil, fmt.Errorf("Invalid $type %q", kind)
+		}
+		if k[0] == 0 {
+			return data, nil
+		}
+		return bson.Binary{Kind: k[0], Data: data}, nil
+	}
+
+	if s, ok := str("$regex"); ok {
+		if opts, ok := str("$options"); ok {
+			return bson.RegEx{Pattern: s, Options: opts}, nil
+		}
+	}
+
+	return doc, nil
+}
+
+// parseQuery parses a query given on the command line. An empty query matches everything
+func parseQuery(query string) (interface{}, error) {
+	if len(query) == 0 {
+		return bson.M{}, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewBufferString(query))
+	dec.UseNumber()
+
+	value, err := unmarshalJSON(dec)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid query: %s", err)
+	}
+	if _, ok := value.(bson.D); !ok {
+		return nil, fmt.Errorf("Invalid query: must be a JSON object")
+	}
+	return value, nil
+}
@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	. "github.com/smartystreets/goconvey/convey"
+	"gopkg.in/mgo.v2/bson"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestExtendedJSON(t *testing.T) {
+	Convey("Extended JSON", t, func() {
+		id := bson.NewObjectId()
+		date := time.Date(2016, 1, 2, 15, 4, 5, 6000000, time.UTC)
+
+		doc := bson.D{
+			{Name: "_id", Value: id},
+			{Name: "name", Value: "Jo \"Bongo\""},
+			{Name: "count", Value: 3},
+			{Name: "big", Value: int64(1) << 40},
+			{Name: "small", Value: int64(2)},
+			{Name: "ratio", Value: 2.0},
+			{Name: "createdAt", Value: date},
+			{Name: "data", Value: []byte("raw")},
+			{Name: "pattern", Value: bson.RegEx{Pattern: "^a", Options: "i"}},
+			{Name: "tags", Value: []interface{}{"a", nil, true}},
+			{Name: "address", Value: bson.D{{Name: "zip", Value: "12345"}}},
+		}
+
+		Convey("should round trip every supported type, in order", func() {
+			buf := &bytes.Buffer{}
+			So(marshalJSON(buf, doc), ShouldEqual, nil)
+			So(buf.String(), ShouldContainSubstring, `"_id":{"$oid":"`+id.Hex()+`"}`)
+			So(buf.String(), ShouldContainSubstring, `"ratio":2.0`)
+
+			dec := json.NewDecoder(buf)
+			dec.UseNumber()
+			value, err := unmarshalJSON(dec)
+			So(err, ShouldEqual, nil)
+
+			loaded := value.(bson.D)
+			So(loaded[0].Value, ShouldEqual, id)
+			So(loaded[1].Value, ShouldEqual, "Jo \"Bongo\"")
+			So(loaded[2].Value, ShouldEqual, 3)
+			So(loaded[3].Value, ShouldEqual, int64(1)<<40)
+			So(loaded[4].Value, ShouldEqual, int64(2))
+			So(loaded[5].Value, ShouldEqual, 2.0)
+			So(loaded[6].Value.(time.Time).Equal(date), ShouldEqual, true)
+			So(loaded[7].Value, ShouldResemble, []byte("raw"))
+			So(loaded[8].Value, ShouldResemble, bson.RegEx{Pattern: "^a", Options: "i"})
+			So(loaded[9].Value, ShouldResemble, []interface{}{"a", nil, true})
+			So(loaded[10].Value, ShouldResemble, bson.D{{Name: "zip", Value: "12345"}})
+
+			_, err = unmarshalJSON(dec)
+			So(err, ShouldEqual, io.EOF)
+		})
+
+		Convey("should not dump unsupported types", func() {
+			err := marshalJSON(&bytes.Buffer{}, bson.D{{Name: "ts", Value: bson.MongoTimestamp(1)}})
+			So(err, ShouldNotEqual, nil)
+		})
+
+		Convey("should parse queries", func() {
+			query, err := parseQuery(`{"_id": {"$oid": "` + id.Hex() + `"}}`)
+			So(err, ShouldEqual, nil)
+			So(query, ShouldResemble, bson.D{{Name: "_id", Value: id}})
+
+			_, err = parseQuery(`[1]`)
+			So(err, ShouldNotEqual, nil)
+
+			_, err = parseQuery(`{"a": `)
+			So(err, ShouldNotEqual, nil)
+		})
+	})
+}
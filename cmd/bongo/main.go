@@ -0,0 +1,9 @@
+// Command bongo runs maintenance tasks against a MongoDB database. It has no migrations or models,
+// so only dump, load and count work. See package cli to build your own
+package main
+
+import "github.com/maxwellhealth/bongo/cli"
+
+func main() {
+	(&cli.App{}).Main()
+}
@@ -1,7 +1,10 @@
 package bongo
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"strings"
 	"time"
 
@@ -39,6 +42,37 @@ type ValidateHook interface {
 	Validate(*Collection) []error
 }
 
+// BeforeSaveCtxHook is used instead of BeforeSaveHook if implemented.
+// The context is the one passed to SaveCtx (or context.Background() for Save)
+type BeforeSaveCtxHook interface {
+	BeforeSaveCtx(context.Context, *Collection) error
+}
+
+// AfterSaveCtxHook ...
+type AfterSaveCtxHook interface {
+	AfterSaveCtx(context.Context, *Collection) error
+}
+
+// BeforeDeleteCtxHook ...
+type BeforeDeleteCtxHook interface {
+	BeforeDeleteCtx(context.Context, *Collection) error
+}
+
+// AfterDeleteCtxHook ...
+type AfterDeleteCtxHook interface {
+	AfterDeleteCtx(context.Context, *Collection) error
+}
+
+// AfterFindCtxHook ...
+type AfterFindCtxHook interface {
+	AfterFindCtx(context.Context, *Collection) error
+}
+
+// ValidateCtxHook ...
+type ValidateCtxHook interface {
+	ValidateCtx(context.Context, *Collection) []error
+}
+
 // ValidationError ...
 type ValidationError struct {
 	Errors []error
@@ -70,11 +104,104 @@ func (v *ValidationError) Error() string {
 	return "Validation failed. (" + strings.Join(errs, ", ") + ")"
 }
 
+// Is ...
+func (v *ValidationError) Is(target error) bool {
+	return target == ErrValidation
+}
+
+// Unwrap ...
+func (v *ValidationError) Unwrap() []error {
+	return v.Errors
+}
+
+// ForField returns the FieldErrors for a bson path, e.g. "address.zip"
+func (v *ValidationError) ForField(path string) []*FieldError {
+	errs := []*FieldError{}
+
+	for _, e := range v.Errors {
+		if fe, ok := e.(*FieldError); ok && fe.Path == path {
+			errs = append(errs, fe)
+		}
+	}
+	return errs
+}
+
+// HasCode checks if any of the FieldErrors has the code, e.g. "required"
+func (v *ValidationError) HasCode(code string) bool {
+	for _, e := range v.Errors {
+		if fe, ok := e.(*FieldError); ok && fe.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// MarshalJSON renders every error as a FieldError, so front ends can map them to form inputs.
+// Plain errors only have a message
+func (v *ValidationError) MarshalJSON() ([]byte, error) {
+	errs := make([]*FieldError, len(v.Errors))
+
+	for i, e := range v.Errors {
+		if fe, ok := e.(*FieldError); ok {
+			errs[i] = fe
+		} else {
+			errs[i] = &FieldError{Message: e.Error()}
+		}
+	}
+
+	return json.Marshal(struct {
+		Message string        `json:"message"`
+		Errors  []*FieldError `json:"errors"`
+	}{"Validation failed", errs})
+}
+
+// FieldError is a validation error for a single field. Validators and Validate hooks
+// can return these in place of plain errors
+type FieldError struct {
+	// The bson path of the field, e.g. "address.zip"
+	Path string `json:"path"`
+	// The Go path of the field, e.g. "Address.Zip"
+	Field string `json:"field"`
+	// The rule that failed, e.g. "required" or "max"
+	Code string `json:"code"`
+	// Parameters of the rule, e.g. {"max": 10}
+	Params  map[string]interface{} `json:"params,omitempty"`
+	Message string                 `json:"message"`
+	// The underlying error, if any (e.g. a *DuplicateKeyError)
+	Err error `json:"-"`
+}
+
+// NewFieldError ...
+func NewFieldError(path, field, code string, params map[string]interface{}, format string, args ...interface{}) *FieldError {
+	return &FieldError{
+		Path:    path,
+		Field:   field,
+		Code:    code,
+		Params:  params,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+func (f *FieldError) Error() string {
+	return f.Message
+}
+
+// Unwrap ...
+func (f *FieldError) Unwrap() error {
+	return f.Err
+}
+
 // Collection ...
 type Collection struct {
 	Name       string
 	Context    *Context
 	Connection *Connection
+	// Run cascades synchronously on Save/Delete and return their errors
+	// (as a *CascadeError) instead of firing them off in a goroutine
+	SyncCascade bool
+	// Exclude soft deleted documents from finds unless WithDeleted or OnlyDeleted is passed.
+	// Defaults to whether Connection.EnableSoftDelete was called for this collection
+	SoftDelete bool
 }
 
 // NewTracker ...
@@ -83,6 +210,13 @@ type NewTracker interface {
 	IsNew() bool
 }
 
+// VersionedDocument is used for optimistic concurrency control. The version must be
+// stored in the "_v" field and is incremented on every save
+type VersionedDocument interface {
+	GetVersion() int
+	SetVersion(int)
+}
+
 // DocumentNotFoundError ...
 type DocumentNotFoundError struct{}
 
@@ -90,6 +224,42 @@ func (d DocumentNotFoundError) Error() string {
 	return "Document not found"
 }
 
+// Is ...
+func (d DocumentNotFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+// ConcurrentModificationError is returned when saving or deleting a versioned document
+// whose stored version no longer matches the one that was loaded
+type ConcurrentModificationError struct {
+	ID      bson.ObjectId
+	Version int
+}
+
+func (c *ConcurrentModificationError) Error() string {
+	return fmt.Sprintf("Document %s was modified concurrently (expected version %d)", c.ID.Hex(), c.Version)
+}
+
+// Is ...
+func (c *ConcurrentModificationError) Is(target error) bool {
+	return target == ErrConcurrentModification
+}
+
+// WithContext returns a shallow copy of the collection whose context is an overlay of this
+// collection's context with the given values (see Context.Overlay). Its Connection is swapped
+// for a copy with the same overlay, so collections used by cascades see the values too
+func (c *Collection) WithContext(values map[string]interface{}) *Collection {
+	overlay := c.Context.Overlay(values)
+
+	conn := *c.Connection
+	conn.Context = overlay
+
+	col := *c
+	col.Connection = &conn
+	col.Context = overlay
+	return &col
+}
+
 // Collection ...
 func (c *Collection) Collection() *mgo.Collection {
 	return c.Connection.Session.DB(c.Connection.DialInfo.Database).C(c.Name)
@@ -100,37 +270,70 @@ func (c *Collection) CollectionOnSession(sess *mgo.Session) *mgo.Collection {
 	return sess.DB(c.Connection.DialInfo.Database).C(c.Name)
 }
 
+// sessionCtx clones the connection's session (per mgo's recommendation, so there is no blocking)
+// and uses the context's deadline as its socket timeout
+func (c *Collection) sessionCtx(ctx context.Context) (*mgo.Session, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, wrapError(err)
+	}
+
+	sess := c.Connection.Session.Clone()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		sess.SetSocketTimeout(time.Until(deadline))
+	}
+	return sess, nil
+}
+
 // PreSave ...
 func (c *Collection) PreSave(doc Document) error {
-	// Validate?
-	if validator, ok := doc.(ValidateHook); ok {
-		errs := validator.Validate(c)
+	return c.PreSaveCtx(context.Background(), doc)
+}
 
-		if len(errs) > 0 {
-			return &ValidationError{errs}
-		}
-	}
+// PreSaveCtx runs validation and the before save hook
+func (c *Collection) PreSaveCtx(ctx context.Context, doc Document) error {
+	// Validate? Tag validators run first, their errors are merged with the hook's
+	errs := ValidateStruct(c, doc)
+	errs = append(errs, runValidate(ctx, c, doc)...)
 
-	if hook, ok := doc.(BeforeSaveHook); ok {
-		err := hook.BeforeSave(c)
-		if err != nil {
-			return err
-		}
+	if len(errs) > 0 {
+		return &ValidationError{errs}
 	}
 
-	return nil
+	return runBeforeSave(ctx, c, doc)
 }
 
 // Save ...
 func (c *Collection) Save(doc Document) error {
-	var err error
-	sess := c.Connection.Session.Clone()
+	return c.SaveCtx(context.Background(), doc)
+}
+
+// SaveCtx saves the document. The context is passed to hooks, and its deadline is used as the socket
+// timeout. Cancellation is checked before writing, mgo operations themselves can't be interrupted
+func (c *Collection) SaveCtx(ctx context.Context, doc Document) error {
+	sess, err := c.sessionCtx(ctx)
+	if err != nil {
+		return err
+	}
 	defer sess.Close()
 
-	// Per mgo's recommendation, create a clone of the session so there is no blocking
 	col := c.CollectionOnSession(sess)
 
-	err = c.PreSave(doc)
+	// Let the hooks know what's being saved, the tracker is reset once the save is done. It goes in
+	// the context rather than on the tracker, so concurrent saves of the document don't mix it up.
+	// Documents that aren't tracked get nil, not the session of a save that cascaded to them
+	var saveSession *DiffTrackingSession
+	if trackable, ok := doc.(Trackable); ok {
+		if tracker := trackable.GetDiffTracker(); tracker != nil {
+			saveSession, err = tracker.NewSession(false)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	ctx = context.WithValue(ctx, saveSessionKey{}, saveSession)
+
+	err = c.PreSaveCtx(ctx, doc)
 	if err != nil {
 		return err
 	}
@@ -151,8 +354,6 @@ func (c *Collection) Save(doc Document) error {
 		tt.SetModified(now)
 	}
 
-	go CascadeSave(c, doc)
-
 	id := doc.GetID()
 
 	if !isNew && !id.Valid() {
@@ -165,65 +366,200 @@ func (c *Collection) Save(doc Document) error {
 		doc.SetID(id)
 	}
 
-	_, err = col.UpsertId(id, doc)
+	if err = ctx.Err(); err != nil {
+		return wrapError(err)
+	}
 
+	err = c.write(col, doc, id, isNew)
 	if err != nil {
 		return err
 	}
 
-	if hook, ok := doc.(AfterSaveHook); ok {
-		err = hook.AfterSave(c)
-		if err != nil {
-			return err
+	err = c.cascadeSave(ctx, doc)
+	if err != nil {
+		// The document itself was written, so it isn't new anymore
+		if newt, ok := doc.(NewTracker); ok {
+			newt.SetIsNew(false)
 		}
+		resetTracker(doc)
+		return err
+	}
+
+	err = runAfterSave(ctx, c, doc)
+	if err != nil {
+		return err
 	}
 
 	// We saved it, no longer new
 	if newt, ok := doc.(NewTracker); ok {
 		newt.SetIsNew(false)
 	}
+	resetTracker(doc)
 
 	return nil
 }
 
+// write persists the document. Existing trackable documents only write the fields that changed,
+// so concurrent writes to other fields aren't clobbered, and existing versioned documents are only
+// written if the stored version still matches. Everything else is upserted in full
+func (c *Collection) write(col *mgo.Collection, doc Document, id bson.ObjectId, isNew bool) (err error) {
+	selector := bson.M{"_id": id}
+
+	if versioned, ok := doc.(VersionedDocument); ok {
+		version := versioned.GetVersion()
+		versioned.SetVersion(version + 1)
+
+		// Put the version back if nothing was written, so the caller can retry
+		defer func() {
+			if err != nil {
+				versioned.SetVersion(version)
+			}
+		}()
+
+		if !isNew {
+			selector["_v"] = versionSelector(version)
+		}
+	}
+
+	var update bson.M
+	if trackable, ok := doc.(Trackable); ok && !isNew {
+		update, err = trackable.GetDiffTracker().GetUpdate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if update == nil && len(selector) == 1 {
+		_, err = col.UpsertId(id, doc)
+	} else if update == nil {
+		err = col.Update(selector, doc)
+	} else if len(update) > 0 {
+		err = col.Update(selector, update)
+	}
+
+	if err == mgo.ErrNotFound {
+		return c.conflictError(col, selector)
+	} else if mgo.IsDup(err) {
+		return c.duplicateKeyError(col, doc, err)
+	}
+	return wrapError(err)
+}
+
+// duplicateKeyError turns a duplicate key error (E11000) into the same ValidationError the unique
+// validators return, naming the fields of the violated index. The *DuplicateKeyError can still be
+// found with errors.As
+func (c *Collection) duplicateKeyError(col *mgo.Collection, doc Document, err error) error {
+	dup := wrapError(err).(*DuplicateKeyError)
+	index := dup.Index
+
+	fields := []string{}
+	if indexes, ierr := col.Indexes(); ierr == nil {
+		for _, idx := range indexes {
+			if idx.Name != index {
+				continue
+			}
+			for _, key := range idx.Key {
+				// Keys look like "name", "-name" or "$text:name"
+				if i := strings.Index(key, ":"); i >= 0 {
+					key = key[i+1:]
+				}
+				fields = append(fields, strings.TrimPrefix(key, "-"))
+			}
+		}
+	}
+
+	dup.Keys = fields
+	fe := uniqueError(doc, fields, map[string]interface{}{"index": index})
+	fe.Err = dup
+
+	return &ValidationError{[]error{fe}}
+}
+
+// versionSelector matches a stored version. Documents saved before they were versioned have no
+// version field, which is the same as version 0
+func versionSelector(version int) interface{} {
+	if version == 0 {
+		return bson.M{"$in": []interface{}{0, nil}}
+	}
+	return version
+}
+
+// conflictError works out why a conditional write didn't match anything: either the
+// document is gone or somebody else changed it first
+func (c *Collection) conflictError(col *mgo.Collection, selector bson.M) error {
+	id := selector["_id"].(bson.ObjectId)
+	count, err := col.FindId(id).Count()
+
+	if err != nil {
+		return wrapError(err)
+	}
+
+	if count == 0 {
+		return &DocumentNotFoundError{}
+	}
+
+	conflict := &ConcurrentModificationError{ID: id}
+	if version, ok := selector["_v"].(int); ok {
+		conflict.Version = version
+	}
+	return conflict
+}
+
 // FindByID ...
-func (c *Collection) FindByID(id bson.ObjectId, doc interface{}) error {
+func (c *Collection) FindByID(id bson.ObjectId, doc interface{}, opts ...FindOption) error {
+	return c.FindByIDCtx(context.Background(), id, doc, opts...)
+}
+
+// FindByIDCtx is FindByID with a context for hooks. Its deadline is used as the query's maxTimeMS
+func (c *Collection) FindByIDCtx(ctx context.Context, id bson.ObjectId, doc interface{}, opts ...FindOption) error {
+	if err := ctx.Err(); err != nil {
+		return wrapError(err)
+	}
+
+	q := c.Collection().Find(c.scopeQuery(bson.M{"_id": id}, opts))
+	setMaxTime(ctx, q)
 
-	err := c.Collection().FindId(id).One(doc)
+	err := q.One(doc)
 
 	// Handle errors coming from mgo - we want to convert it to a DocumentNotFoundError so people can figure out
 	// what the error type is without looking at the text
 	if err != nil {
-		if err.Error() == "not found" {
-			return &DocumentNotFoundError{}
-		}
-		return err
+		return wrapError(err)
 	}
 
-	if hook, ok := doc.(AfterFindHook); ok {
-		err = hook.AfterFind(c)
-		if err != nil {
-			return err
-		}
+	err = runAfterFind(ctx, c, doc)
+	if err != nil {
+		return err
 	}
 
 	// We retrieved it, so set new to false
 	if newt, ok := doc.(NewTracker); ok {
 		newt.SetIsNew(false)
 	}
+	resetTracker(doc)
 	return nil
 }
 
 // Find doesn't actually do any DB interaction, it just creates the result set so we can
 // start looping through on the iterator
-func (c *Collection) Find(query interface{}) *ResultSet {
+func (c *Collection) Find(query interface{}, opts ...FindOption) *ResultSet {
+	return c.FindCtx(context.Background(), query, opts...)
+}
+
+// FindCtx is Find with a context. The context is checked on every call to Next and passed to the
+// AfterFind hooks, and its deadline is used as the query's maxTimeMS. Soft deleted documents are
+// excluded if the collection has SoftDelete set
+func (c *Collection) FindCtx(ctx context.Context, query interface{}, opts ...FindOption) *ResultSet {
 	col := c.Collection()
+	query = c.scopeQuery(query, opts)
 
 	// Count for testing
 	q := col.Find(query)
+	setMaxTime(ctx, q)
 
 	resultset := new(ResultSet)
 
+	resultset.ctx = ctx
 	resultset.Query = q
 	resultset.Params = query
 	resultset.Collection = c
@@ -232,10 +568,15 @@ func (c *Collection) Find(query interface{}) *ResultSet {
 }
 
 // FindOne ...
-func (c *Collection) FindOne(query interface{}, doc interface{}) error {
+func (c *Collection) FindOne(query interface{}, doc interface{}, opts ...FindOption) error {
+	return c.FindOneCtx(context.Background(), query, doc, opts...)
+}
+
+// FindOneCtx is FindOne with a context, see FindCtx
+func (c *Collection) FindOneCtx(ctx context.Context, query interface{}, doc interface{}, opts ...FindOption) error {
 
 	// Now run a find
-	results := c.Find(query)
+	results := c.FindCtx(ctx, query, opts...)
 	results.Query.Limit(1)
 
 	hasNext := results.Next(doc)
@@ -256,36 +597,98 @@ func (c *Collection) FindOne(query interface{}, doc interface{}) error {
 }
 
 // Delete ...
+// SoftDeletable documents are only marked as deleted. Use Purge to remove them for good
 func (c *Collection) Delete(doc Document) error {
-	var err error
-	// Create a new session per mgo's suggestion to avoid blocking
-	sess := c.Connection.Session.Clone()
+	return c.DeleteCtx(context.Background(), doc)
+}
+
+// DeleteCtx is Delete with a context, see SaveCtx
+func (c *Collection) DeleteCtx(ctx context.Context, doc Document) error {
+	_, soft := doc.(SoftDeletable)
+	return c.delete(ctx, doc, !soft)
+}
+
+func (c *Collection) delete(ctx context.Context, doc Document, hard bool) error {
+	sess, err := c.sessionCtx(ctx)
+	if err != nil {
+		return err
+	}
 	defer sess.Close()
 	col := c.CollectionOnSession(sess)
 
-	if hook, ok := doc.(BeforeDeleteHook); ok {
-		err := hook.BeforeDelete(c)
-		if err != nil {
-			return err
+	ctx, err = enterCascade(ctx, c, doc.GetID())
+	if err != nil {
+		return err
+	}
+
+	// A refused delete mustn't run the BeforeDelete hook
+	if err = checkDeleteRestrictions(c, doc); err != nil {
+		return err
+	}
+
+	err = runBeforeDelete(ctx, c, doc)
+	if err != nil {
+		return err
+	}
+
+	if err = ctx.Err(); err != nil {
+		return wrapError(err)
+	}
+
+	if hard {
+		// A stale copy of a versioned document must not delete a newer one
+		selector := bson.M{"_id": doc.GetID()}
+		if versioned, ok := doc.(VersionedDocument); ok {
+			selector["_v"] = versionSelector(versioned.GetVersion())
 		}
+
+		err = col.Remove(selector)
+
+		if err == mgo.ErrNotFound && len(selector) > 1 {
+			err = c.conflictError(col, selector)
+		} else {
+			err = wrapError(err)
+		}
+	} else {
+		err = c.setDeleted(col, doc.(SoftDeletable), time.Now())
 	}
 
-	err = col.Remove(bson.M{"_id": doc.GetID()})
+	if err != nil {
+		return err
+	}
 
+	err = c.cascadeDelete(ctx, doc)
 	if err != nil {
 		return err
 	}
 
-	go CascadeDelete(c, doc)
+	return runAfterDelete(ctx, c, doc)
+}
 
-	if hook, ok := doc.(AfterDeleteHook); ok {
-		err = hook.AfterDelete(c)
-		if err != nil {
+// cascadeSave runs CascadeSave in the background, or inline if SyncCascade is set or it's part of a
+// running cascade
+func (c *Collection) cascadeSave(ctx context.Context, doc Document) error {
+	if !c.SyncCascade && !nestedCascade(ctx) {
+		// Build the configs before the save returns and the diff tracker is reset, or the cascade
+		// wouldn't see what changed
+		toCascade, err := buildCascadeConfigs(c, doc)
+		if err != nil || len(toCascade) == 0 {
 			return err
 		}
+		go cascadeSaveCtx(detachCascade(ctx), c, doc, toCascade)
+		return nil
 	}
+	return cascadeSaveCtx(ctx, c, doc, nil)
+}
 
-	return nil
+// cascadeDelete runs CascadeDelete in the background, or inline if SyncCascade is set or it's part
+// of a running cascade
+func (c *Collection) cascadeDelete(ctx context.Context, doc Document) error {
+	if !c.SyncCascade && !nestedCascade(ctx) {
+		go cascadeDeleteCtx(detachCascade(ctx), c, doc)
+		return nil
+	}
+	return cascadeDeleteCtx(ctx, c, doc)
 }
 
 // RawDelete convenience method which just delegates to mgo.
@@ -294,7 +697,8 @@ func (c *Collection) RawDelete(query bson.M) (*mgo.ChangeInfo, error) {
 	sess := c.Connection.Session.Clone()
 	defer sess.Close()
 	col := c.CollectionOnSession(sess)
-	return col.RemoveAll(query)
+	info, err := col.RemoveAll(query)
+	return info, wrapError(err)
 }
 
 // RawDeleteOne convenience method which just delegates to mgo.
@@ -303,5 +707,5 @@ func (c *Collection) RawDeleteOne(query bson.M) error {
 	sess := c.Connection.Session.Clone()
 	defer sess.Close()
 	col := c.CollectionOnSession(sess)
-	return col.Remove(query)
+	return wrapError(col.Remove(query))
 }
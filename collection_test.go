@@ -1,6 +1,7 @@
 package bongo
 
 import (
+	"context"
 	"errors"
 	. "github.com/smartystreets/goconvey/convey"
 	"gopkg.in/mgo.v2/bson"
@@ -51,6 +52,76 @@ func (h *hookedDocument) AfterFind(c *Collection) error {
 	return nil
 }
 
+type trackedDocument struct {
+	DocumentBase `bson:",inline"`
+	Name         string
+	Email        string
+	Tags         []string
+	Meta         map[string]string
+	Address      *trackedAddress
+	diffTracker  *DiffTracker
+}
+
+type trackedAddress struct {
+	Zip string
+}
+
+func (t *trackedDocument) GetDiffTracker() *DiffTracker {
+	if t.diffTracker == nil {
+		t.diffTracker = NewDiffTracker(t)
+	}
+	return t.diffTracker
+}
+
+type savedFieldsDocument struct {
+	DocumentBase  `bson:",inline"`
+	Name          string
+	Email         string
+	BeforeChanged []string `bson:"-"`
+	AfterChanged  []string `bson:"-"`
+	diffTracker   *DiffTracker
+}
+
+func (s *savedFieldsDocument) GetDiffTracker() *DiffTracker {
+	if s.diffTracker == nil {
+		s.diffTracker = NewDiffTracker(s)
+	}
+	return s.diffTracker
+}
+
+func (s *savedFieldsDocument) BeforeSaveCtx(ctx context.Context, c *Collection) error {
+	s.BeforeChanged = SaveSession(ctx).ChangedFields
+	return nil
+}
+
+func (s *savedFieldsDocument) AfterSaveCtx(ctx context.Context, c *Collection) error {
+	s.AfterChanged = SaveSession(ctx).ChangedFields
+	return nil
+}
+
+type versionedDocument struct {
+	VersionedDocumentBase `bson:",inline"`
+	Name                  string
+}
+
+type ctxKey string
+
+type ctxHookedDocument struct {
+	DocumentBase  `bson:",inline"`
+	SavedBy       interface{} `bson:"-"`
+	RanBeforeSave bool        `bson:"-"`
+}
+
+func (h *ctxHookedDocument) BeforeSave(c *Collection) error {
+	h.RanBeforeSave = true
+	return nil
+}
+
+func (h *ctxHookedDocument) BeforeSaveCtx(ctx context.Context, c *Collection) error {
+	h.SavedBy = ctx.Value(ctxKey("user"))
+	return nil
+}
+
 type validatedDocument struct {
 	DocumentBase `bson:",inline"`
 	Name         string
@@ -115,6 +186,120 @@ func TestCollection(t *testing.T) {
 			So(count, ShouldEqual, 1)
 		})
 
+		Convey("should only update changed fields of an existing trackable document", func() {
+			doc := &trackedDocument{}
+			doc.Name = "foo"
+			doc.Email = "foo@example.com"
+
+			err := conn.Collection("tests").Save(doc)
+			So(err, ShouldEqual, nil)
+
+			// Another writer changes a different field in the meantime
+			err = conn.Collection("tests").Collection().UpdateId(doc.ID, bson.M{
+				"$set": bson.M{"email": "bar@example.com"},
+			})
+			So(err, ShouldEqual, nil)
+
+			doc.GetDiffTracker().Reset()
+			doc.Name = "bar"
+			err = conn.Collection("tests").Save(doc)
+			So(err, ShouldEqual, nil)
+
+			newDoc := &trackedDocument{}
+			err = conn.Collection("tests").FindByID(doc.ID, newDoc)
+			So(err, ShouldEqual, nil)
+			So(newDoc.Name, ShouldEqual, "bar")
+			So(newDoc.Email, ShouldEqual, "bar@example.com")
+		})
+
+		Convey("should save in place changes to slices, maps and pointers of a trackable document", func() {
+			doc := &trackedDocument{
+				Tags:    []string{"a"},
+				Meta:    map[string]string{"k": "v"},
+				Address: &trackedAddress{Zip: "1"},
+			}
+			err := conn.Collection("tests").Save(doc)
+			So(err, ShouldEqual, nil)
+
+			doc.Tags[0] = "b"
+			doc.Meta["k"] = "w"
+			doc.Address.Zip = "2"
+			err = conn.Collection("tests").Save(doc)
+			So(err, ShouldEqual, nil)
+
+			newDoc := &trackedDocument{}
+			err = conn.Collection("tests").FindByID(doc.ID, newDoc)
+			So(err, ShouldEqual, nil)
+			So(newDoc.Tags, ShouldResemble, []string{"b"})
+			So(newDoc.Meta, ShouldResemble, map[string]string{"k": "w"})
+			So(newDoc.Address.Zip, ShouldEqual, "2")
+		})
+
+		Convey("should reset the diff tracker after loading and saving", func() {
+			doc := &trackedDocument{}
+			doc.Name = "foo"
+
+			err := conn.Collection("tests").Save(doc)
+			So(err, ShouldEqual, nil)
+			So(doc.GetDiffTracker().Modified("Name"), ShouldEqual, false)
+
+			loaded := &trackedDocument{}
+			err = conn.Collection("tests").FindByID(doc.ID, loaded)
+			So(err, ShouldEqual, nil)
+
+			isNew, changed := loaded.GetDiffTracker().GetModified(false)
+			So(isNew, ShouldEqual, false)
+			So(changed, ShouldBeEmpty)
+
+			loaded.Name = "bar"
+			So(loaded.GetDiffTracker().Modified("Name"), ShouldEqual, true)
+		})
+
+		Convey("should tell the save hooks what changed", func() {
+			doc := &savedFieldsDocument{}
+			doc.Name = "foo"
+
+			err := conn.Collection("tests").Save(doc)
+			So(err, ShouldEqual, nil)
+
+			doc.Email = "foo@example.com"
+			err = conn.Collection("tests").Save(doc)
+			So(err, ShouldEqual, nil)
+			So(doc.BeforeChanged, ShouldResemble, []string{"Email"})
+			So(doc.AfterChanged, ShouldResemble, []string{"Email"})
+		})
+
+		Convey("should increment the version and refuse to save a stale versioned document", func() {
+			doc := &versionedDocument{}
+			doc.Name = "foo"
+
+			err := conn.Collection("tests").Save(doc)
+			So(err, ShouldEqual, nil)
+			So(doc.Version, ShouldEqual, 1)
+
+			stale := &versionedDocument{}
+			err = conn.Collection("tests").FindByID(doc.ID, stale)
+			So(err, ShouldEqual, nil)
+
+			doc.Name = "bar"
+			err = conn.Collection("tests").Save(doc)
+			So(err, ShouldEqual, nil)
+			So(doc.Version, ShouldEqual, 2)
+
+			stale.Name = "baz"
+			err = conn.Collection("tests").Save(stale)
+			_, ok := err.(*ConcurrentModificationError)
+			So(ok, ShouldEqual, true)
+			So(stale.Version, ShouldEqual, 1)
+
+			err = conn.Collection("tests").Delete(stale)
+			_, ok = err.(*ConcurrentModificationError)
+			So(ok, ShouldEqual, true)
+
+			err = conn.Collection("tests").Delete(doc)
+			So(err, ShouldEqual, nil)
+		})
+
 		Convey("should set created and modified dates", func() {
 
 			doc := &noHookDocument{}
@@ -129,6 +314,29 @@ func TestCollection(t *testing.T) {
 			So(doc.ModifiedAt.UnixNano(), ShouldBeGreaterThan, doc.CreatedAt.UnixNano())
 		})
 
+		Convey("should pass the context to hooks that take one", func() {
+			doc := &ctxHookedDocument{}
+			ctx := context.WithValue(context.Background(), ctxKey("user"), "jane")
+
+			err := conn.Collection("tests").SaveCtx(ctx, doc)
+			So(err, ShouldEqual, nil)
+			So(doc.SavedBy, ShouldEqual, "jane")
+			So(doc.RanBeforeSave, ShouldEqual, false)
+		})
+
+		Convey("should not save with a cancelled context", func() {
+			doc := &noHookDocument{}
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			err := conn.Collection("tests").SaveCtx(ctx, doc)
+			So(err, ShouldEqual, context.Canceled)
+
+			count, err := conn.Collection("tests").Collection().Count()
+			So(err, ShouldEqual, nil)
+			So(count, ShouldEqual, 0)
+		})
+
 		Reset(func() {
 			conn.Session.DB("bongotest").DropDatabase()
 		})
@@ -159,6 +367,7 @@ func TestCollection(t *testing.T) {
 			err := conn.Collection("tests").FindByID(bson.NewObjectId(), doc)
 			_, ok := err.(*DocumentNotFoundError)
 			So(ok, ShouldEqual, true)
+			So(errors.Is(err, ErrNotFound), ShouldEqual, true)
 		})
 
 		Reset(func() {
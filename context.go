@@ -1,21 +1,79 @@
 package bongo
 
-import ()
+import (
+	"gopkg.in/mgo.v2/bson"
+	"sync"
+)
 
-// Context struct
+// Context struct. It is safe for concurrent use
 type Context struct {
-	set map[string]interface{}
+	set    map[string]interface{}
+	parent *Context
+	mutex  sync.RWMutex
+}
+
+// NewContext ...
+func NewContext() *Context {
+	return &Context{
+		set: make(map[string]interface{}),
+	}
+}
+
+// Overlay returns a new context with the given values. Lookups fall back to this context for keys
+// that aren't set on the overlay, and setting values on the overlay doesn't change this context
+func (c *Context) Overlay(values map[string]interface{}) *Context {
+	overlay := NewContext()
+	overlay.parent = c
+
+	for key, value := range values {
+		overlay.set[key] = value
+	}
+	return overlay
 }
 
 // Get ...
 func (c *Context) Get(key string) interface{} {
-	if value, ok := c.set[key]; ok {
+	c.mutex.RLock()
+	value, ok := c.set[key]
+	c.mutex.RUnlock()
+
+	if ok {
 		return value
 	}
+	if c.parent != nil {
+		return c.parent.Get(key)
+	}
 	return nil
 }
 
+// GetString returns the value if it is a string, otherwise ""
+func (c *Context) GetString(key string) string {
+	value, _ := c.Get(key).(string)
+	return value
+}
+
+// GetObjectId returns the value if it is a bson.ObjectId, otherwise ""
+func (c *Context) GetObjectId(key string) bson.ObjectId {
+	value, _ := c.Get(key).(bson.ObjectId)
+	return value
+}
+
 // Set ...
 func (c *Context) Set(key string, value interface{}) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.set == nil {
+		c.set = make(map[string]interface{})
+	}
 	c.set[key] = value
 }
+
+// Delete removes the key from this context. Values of a parent context are not affected,
+// so Get will return the parent's value for the key afterwards, if it has one
+func (c *Context) Delete(key string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	delete(c.set, key)
+}
@@ -0,0 +1,78 @@
+package bongo
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"gopkg.in/mgo.v2/bson"
+	"sync"
+	"testing"
+)
+
+func TestContext(t *testing.T) {
+	Convey("Context", t, func() {
+		ctx := NewContext()
+		ctx.Set("foo", "bar")
+
+		Convey("should get typed values", func() {
+			id := bson.NewObjectId()
+			ctx.Set("id", id)
+
+			So(ctx.GetString("foo"), ShouldEqual, "bar")
+			So(ctx.GetString("id"), ShouldEqual, "")
+			So(ctx.GetObjectId("id"), ShouldEqual, id)
+			So(ctx.GetObjectId("missing"), ShouldEqual, bson.ObjectId(""))
+		})
+
+		Convey("should delete keys", func() {
+			ctx.Delete("foo")
+			So(ctx.Get("foo"), ShouldEqual, nil)
+		})
+
+		Convey("should fall back to the parent in an overlay without changing it", func() {
+			overlay := ctx.Overlay(map[string]interface{}{
+				"user": "jane",
+			})
+
+			So(overlay.Get("foo"), ShouldEqual, "bar")
+			So(overlay.Get("user"), ShouldEqual, "jane")
+			So(ctx.Get("user"), ShouldEqual, nil)
+
+			overlay.Set("foo", "baz")
+			So(overlay.Get("foo"), ShouldEqual, "baz")
+			So(ctx.Get("foo"), ShouldEqual, "bar")
+
+			overlay.Delete("foo")
+			So(overlay.Get("foo"), ShouldEqual, "bar")
+		})
+
+		Convey("should be safe for concurrent use", func() {
+			var wg sync.WaitGroup
+			for i := 0; i < 10; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					ctx.Set("count", i)
+					ctx.Get("count")
+				}(i)
+			}
+			wg.Wait()
+			So(ctx.Get("count"), ShouldNotBeNil)
+		})
+	})
+
+	Convey("Connection/Collection WithContext", t, func() {
+		conn := &Connection{
+			Context: NewContext(),
+		}
+		conn.Context.Set("foo", "bar")
+
+		scoped := conn.WithContext(map[string]interface{}{"user": "jane"})
+		So(scoped.Collection("tests").Context.Get("user"), ShouldEqual, "jane")
+		So(scoped.Collection("tests").Context.Get("foo"), ShouldEqual, "bar")
+		So(conn.Collection("tests").Context.Get("user"), ShouldEqual, nil)
+
+		col := conn.Collection("tests").WithContext(map[string]interface{}{"tenant": "acme"})
+		So(col.Context.Get("tenant"), ShouldEqual, "acme")
+		So(col.Connection.Collection("other").Context.Get("tenant"), ShouldEqual, "acme")
+		So(conn.Context.Get("tenant"), ShouldEqual, nil)
+	})
+}
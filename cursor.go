@@ -0,0 +1,370 @@
+package bongo
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// CursorInfo ...
+type CursorInfo struct {
+	PerPage       int `json:"perPage"`
+	RecordsOnPage int `json:"recordsOnPage"`
+	// Cursors for the pages after and before this one. Empty if there is no such page
+	Next     string `json:"next,omitempty"`
+	Previous string `json:"previous,omitempty"`
+}
+
+// Directions a cursor can point in
+const (
+	cursorForward  = iota
+	cursorBackward = iota
+)
+
+// Length of the truncated HMAC-SHA256 signature on cursors
+const cursorMacSize = 16
+
+// cursorToken is the signed content of a cursor
+type cursorToken struct {
+	Collection string        `bson:"c"`
+	Sort       []string      `bson:"s"`
+	Direction  int           `bson:"d"`
+	Values     []interface{} `bson:"v"`
+	// Hash of the query the cursor was issued for, see queryHash
+	Query []byte `bson:"q"`
+	// The page includes the document with exactly Values
+	Inclusive bool `bson:"i,omitempty"`
+}
+
+type sortField struct {
+	name string
+	desc bool
+}
+
+var (
+	defaultCursorSecret     []byte
+	defaultCursorSecretOnce sync.Once
+)
+
+// cursorSecret returns the connection's CursorSecret, or a random key for this process
+func (r *ResultSet) cursorSecret() []byte {
+	if secret := r.Collection.Connection.CursorSecret; len(secret) > 0 {
+		return secret
+	}
+
+	defaultCursorSecretOnce.Do(func() {
+		defaultCursorSecret = make([]byte, 32)
+		if _, err := rand.Read(defaultCursorSecret); err != nil {
+			panic(err)
+		}
+	})
+	return defaultCursorSecret
+}
+
+// PaginateAfter restricts the query to the page of perPage documents that follows (or precedes)
+// cursor in the order of sortFields ("field" or "-field", defaulting to the ones set with Sort),
+// and returns the cursors for the pages around it. An empty cursor starts from the first page.
+// Unlike Paginate this doesn't count or skip, so it stays fast on deep pages and doesn't shift
+// when documents are inserted. The page is loaded with a single query, and then read with Next.
+// _id is added as a tie breaker if it's not one of the sort fields. Sort fields should never be null
+func (r *ResultSet) PaginateAfter(cursor string, perPage int, sortFields ...string) (*CursorInfo, error) {
+	info := &CursorInfo{PerPage: perPage}
+
+	ctx := r.context()
+	if err := ctx.Err(); err != nil {
+		return info, wrapError(err)
+	}
+
+	if perPage < 1 {
+		return info, errors.New("perPage must be at least 1")
+	}
+
+	if len(sortFields) == 0 {
+		sortFields = r.sort
+	}
+
+	fields := parseSortFields(sortFields)
+	secret := r.cursorSecret()
+
+	hash, err := queryHash(r.Params)
+	if err != nil {
+		return info, err
+	}
+
+	token := &cursorToken{Direction: cursorForward}
+	if cursor != "" {
+		if token, err = decodeCursor(cursor, secret); err != nil {
+			return info, err
+		}
+		if token.Collection != r.Collection.Name || !reflect.DeepEqual(token.Sort, sortStrings(fields)) ||
+			len(token.Values) != len(fields) || !bytes.Equal(token.Query, hash) {
+			return info, ErrInvalidCursor
+		}
+	}
+
+	backward := token.Direction == cursorBackward
+
+	// Load the page, plus one more document to know if there's another page. Going backward, the
+	// page is read in reverse from the cursor
+	var query interface{} = r.Params
+	if cursor != "" {
+		query = andParams(query, keysetQuery(fields, token.Values, !backward, token.Inclusive))
+	}
+
+	sort := sortStrings(fields)
+	if backward {
+		sort = sortStrings(reverseSortFields(fields))
+	}
+
+	q := r.Collection.Collection().Find(query).Sort(sort...).Limit(perPage + 1)
+	if r.selector != nil {
+		q.Select(cursorSelector(r.selector, fields))
+	}
+	if len(r.hint) > 0 {
+		q.Hint(r.hint...)
+	}
+	setMaxTime(ctx, q)
+
+	docs := []bson.Raw{}
+	if err := q.All(&docs); err != nil {
+		return info, wrapError(err)
+	}
+
+	more := len(docs) > perPage
+	if more {
+		docs = docs[:perPage]
+	}
+
+	if backward {
+		for i, j := 0, len(docs)-1; i < j; i, j = i+1, j-1 {
+			docs[i], docs[j] = docs[j], docs[i]
+		}
+	}
+
+	info.RecordsOnPage = len(docs)
+
+	// Next reads the page that was just loaded, and mustn't rescope or run the query again
+	r.Query = q
+	r.Iter = nil
+	r.loadedIter = false
+	r.page = docs
+	r.paged = true
+
+	newToken := func(direction int, values []interface{}, inclusive bool) (string, error) {
+		return encodeCursor(&cursorToken{
+			Collection: r.Collection.Name,
+			Sort:       sortStrings(fields),
+			Direction:  direction,
+			Values:     values,
+			Query:      hash,
+			Inclusive:  inclusive,
+		}, secret)
+	}
+
+	if len(docs) == 0 {
+		// Nothing left in this direction (the documents were removed since the cursor was issued).
+		// Point back the other way, from and including where the cursor was
+		switch {
+		case cursor == "":
+		case backward:
+			info.Next, err = newToken(cursorForward, token.Values, true)
+		default:
+			info.Previous, err = newToken(cursorBackward, token.Values, true)
+		}
+		return info, err
+	}
+
+	first, err := rawSortValues(docs[0], fields)
+	if err != nil {
+		return info, err
+	}
+	last, err := rawSortValues(docs[len(docs)-1], fields)
+	if err != nil {
+		return info, err
+	}
+
+	if (!backward && more) || (backward && cursor != "") {
+		if info.Next, err = newToken(cursorForward, last, false); err != nil {
+			return info, err
+		}
+	}
+
+	if (backward && more) || (!backward && cursor != "") {
+		info.Previous, err = newToken(cursorBackward, first, false)
+	}
+
+	return info, err
+}
+
+// queryHash identifies the query of a result set, so a cursor can't be used with another filter.
+// encoding/json sorts map keys, unlike bson
+func queryHash(params interface{}) ([]byte, error) {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(data)
+	return sum[:cursorMacSize], nil
+}
+
+// parseSortFields parses mgo style sort keys and adds _id as the final tie breaker
+func parseSortFields(keys []string) []sortField {
+	fields := []sortField{}
+	hasID := false
+
+	for _, key := range keys {
+		f := sortField{name: strings.TrimPrefix(strings.TrimPrefix(key, "+"), "-"), desc: strings.HasPrefix(key, "-")}
+		if f.name == "" {
+			continue
+		}
+		if f.name == "_id" {
+			hasID = true
+		}
+		fields = append(fields, f)
+	}
+
+	if !hasID {
+		fields = append(fields, sortField{name: "_id"})
+	}
+	return fields
+}
+
+func reverseSortFields(fields []sortField) []sortField {
+	reversed := make([]sortField, len(fields))
+	for i, f := range fields {
+		reversed[i] = sortField{f.name, !f.desc}
+	}
+	return reversed
+}
+
+func sortStrings(fields []sortField) []string {
+	keys := make([]string, len(fields))
+	for i, f := range fields {
+		if f.desc {
+			keys[i] = "-" + f.name
+		} else {
+			keys[i] = f.name
+		}
+	}
+	return keys
+}
+
+func rawSortValues(raw bson.Raw, fields []sortField) ([]interface{}, error) {
+	doc := bson.M{}
+	if err := raw.Unmarshal(doc); err != nil {
+		return nil, err
+	}
+
+	values := make([]interface{}, len(fields))
+	for i, f := range fields {
+		values[i], _ = lookupBsonPath(doc, strings.Split(f.name, "."))
+	}
+	return values, nil
+}
+
+// cursorSelector adds the sort fields to an inclusive projection, so the cursors can be built
+func cursorSelector(selector interface{}, fields []sortField) interface{} {
+	m, ok := selector.(bson.M)
+	if !ok {
+		return selector
+	}
+
+	inclusive := false
+	for key, v := range m {
+		if key != "_id" && v != 0 && v != false {
+			inclusive = true
+		}
+	}
+	if !inclusive {
+		return selector
+	}
+
+	extended := bson.M{}
+	for key, v := range m {
+		extended[key] = v
+	}
+	for _, f := range fields {
+		extended[f.name] = 1
+	}
+	return extended
+}
+
+// keysetQuery matches documents that sort after (or before) the given values. With inclusive the
+// document with exactly those values matches too
+func keysetQuery(fields []sortField, values []interface{}, after bool, inclusive bool) bson.M {
+	or := []interface{}{}
+
+	for i, f := range fields {
+		clause := bson.M{}
+		for j := 0; j < i; j++ {
+			clause[fields[j].name] = values[j]
+		}
+
+		op := "$lt"
+		if after != f.desc {
+			op = "$gt"
+		}
+		if inclusive && i == len(fields)-1 {
+			op += "e"
+		}
+
+		clause[f.name] = bson.M{op: values[i]}
+		or = append(or, clause)
+	}
+
+	return bson.M{"$or": or}
+}
+
+// andParams adds a condition to the query of a result set, which may be nil
+func andParams(params interface{}, cond bson.M) interface{} {
+	switch p := params.(type) {
+	case nil:
+		return cond
+	case bson.M:
+		return andQuery(p, cond)
+	}
+	return bson.M{"$and": []interface{}{params, cond}}
+}
+
+func encodeCursor(token *cursorToken, secret []byte) (string, error) {
+	data, err := bson.Marshal(token)
+	if err != nil {
+		return "", err
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(data)
+
+	return base64.RawURLEncoding.EncodeToString(append(mac.Sum(nil)[:cursorMacSize], data...)), nil
+}
+
+func decodeCursor(cursor string, secret []byte) (*cursorToken, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || len(raw) <= cursorMacSize {
+		return nil, ErrInvalidCursor
+	}
+
+	sig, data := raw[:cursorMacSize], raw[cursorMacSize:]
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(data)
+	if !hmac.Equal(sig, mac.Sum(nil)[:cursorMacSize]) {
+		return nil, ErrInvalidCursor
+	}
+
+	token := &cursorToken{}
+	if err := bson.Unmarshal(data, token); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return token, nil
+}
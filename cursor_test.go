@@ -0,0 +1,88 @@
+package bongo
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestCursor(t *testing.T) {
+	Convey("Cursors", t, func() {
+		secret := []byte("secret")
+		token := &cursorToken{
+			Collection: "tests",
+			Sort:       []string{"-name", "_id"},
+			Direction:  cursorBackward,
+			Values:     []interface{}{"foo", bson.ObjectIdHex("5a1b2c3d4e5f6a7b8c9d0e1f")},
+			Query:      []byte("hash"),
+		}
+
+		Convey("should round trip through encode and decode", func() {
+			cursor, err := encodeCursor(token, secret)
+			So(err, ShouldBeNil)
+
+			decoded, err := decodeCursor(cursor, secret)
+			So(err, ShouldBeNil)
+			So(decoded, ShouldResemble, token)
+		})
+
+		Convey("should reject cursors that were tampered with or signed with another key", func() {
+			cursor, _ := encodeCursor(token, secret)
+
+			_, err := decodeCursor(cursor, []byte("other"))
+			So(err, ShouldEqual, ErrInvalidCursor)
+
+			tampered := []byte(cursor)
+			tampered[len(tampered)-2]++
+			_, err = decodeCursor(string(tampered), secret)
+			So(err, ShouldEqual, ErrInvalidCursor)
+
+			_, err = decodeCursor("not a cursor", secret)
+			So(err, ShouldEqual, ErrInvalidCursor)
+		})
+
+		Convey("should hash queries regardless of key order", func() {
+			a, err := queryHash(bson.M{"name": "foo", "age": bson.M{"$gt": 1, "$lt": 9}})
+			So(err, ShouldBeNil)
+			b, _ := queryHash(bson.M{"age": bson.M{"$lt": 9, "$gt": 1}, "name": "foo"})
+			So(a, ShouldResemble, b)
+
+			c, _ := queryHash(bson.M{"name": "bar", "age": bson.M{"$gt": 1, "$lt": 9}})
+			So(c, ShouldNotResemble, a)
+		})
+
+		Convey("should add _id as a tie breaker", func() {
+			So(sortStrings(parseSortFields([]string{"-name", "age"})), ShouldResemble, []string{"-name", "age", "_id"})
+			So(sortStrings(parseSortFields([]string{"-_id"})), ShouldResemble, []string{"-_id"})
+		})
+
+		Convey("should read the sort values of a loaded document", func() {
+			data, _ := bson.Marshal(bson.M{"name": "foo", "meta": bson.M{"rank": 2}, "_id": 5})
+			values, err := rawSortValues(bson.Raw{Kind: 3, Data: data}, parseSortFields([]string{"-meta.rank", "name"}))
+			So(err, ShouldBeNil)
+			So(values, ShouldResemble, []interface{}{2, "foo", 5})
+		})
+
+		Convey("should add the sort fields to inclusive projections only", func() {
+			fields := parseSortFields([]string{"-name"})
+			So(cursorSelector(bson.M{"email": 1}, fields), ShouldResemble, bson.M{"email": 1, "name": 1, "_id": 1})
+			So(cursorSelector(bson.M{"email": 0}, fields), ShouldResemble, bson.M{"email": 0})
+		})
+
+		Convey("should build keyset queries", func() {
+			fields := parseSortFields([]string{"-name"})
+			values := []interface{}{"foo", 5}
+
+			So(keysetQuery(fields, values, true, false), ShouldResemble, bson.M{"$or": []interface{}{
+				bson.M{"name": bson.M{"$lt": "foo"}},
+				bson.M{"name": "foo", "_id": bson.M{"$gt": 5}},
+			}})
+
+			So(keysetQuery(fields, values, false, true), ShouldResemble, bson.M{"$or": []interface{}{
+				bson.M{"name": bson.M{"$gt": "foo"}},
+				bson.M{"name": "foo", "_id": bson.M{"$lte": 5}},
+			}})
+		})
+	})
+}
@@ -1,8 +1,10 @@
 package bongo
 
 import (
+	"context"
 	"fmt"
 	"github.com/maxwellhealth/go-dotaccess"
+	"gopkg.in/mgo.v2/bson"
 	"reflect"
 	"strings"
 )
@@ -11,6 +13,10 @@ import (
 type DiffTracker struct {
 	original interface{}
 	current  interface{}
+
+	// original as stored in the database. The struct copy in original shares slices, maps and
+	// pointers with current, so in place changes to those only show up against this
+	snapshot bson.M
 }
 
 // Trackable interface
@@ -50,6 +56,111 @@ func (d *DiffTracker) NewSession(useBsonTags bool) (*DiffTrackingSession, error)
 func (d *DiffTracker) Reset() {
 	// Store a copy of current
 	d.original = reflect.Indirect(reflect.ValueOf(d.current)).Interface()
+	d.snapshot, _ = toBsonMap(d.current)
+}
+
+type saveSessionKey struct{}
+
+// SaveSession returns the fields (by struct field name) that had changed when the save started,
+// from the context SaveCtx passes to the BeforeSaveCtx and AfterSaveCtx hooks. Save resets the
+// tracker once it's done, so the tracker itself doesn't tell what was saved by then. It's nil
+// outside of the save of a Trackable document
+func SaveSession(ctx context.Context) *DiffTrackingSession {
+	sess, _ := ctx.Value(saveSessionKey{}).(*DiffTrackingSession)
+	return sess
+}
+
+// resetTracker resets the DiffTracker of a Trackable document, so that changes are tracked from
+// what was just loaded or saved
+func resetTracker(doc interface{}) {
+	if trackable, ok := doc.(Trackable); ok {
+		if tracker := trackable.GetDiffTracker(); tracker != nil {
+			tracker.Reset()
+		}
+	}
+}
+
+// GetUpdate builds a $set/$unset update document that only touches the bson paths
+// that changed since the last Reset. It returns nil if there is no original to compare
+// against (the document should be written in full)
+func (d *DiffTracker) GetUpdate() (bson.M, error) {
+	if d.original == nil || d.snapshot == nil {
+		return nil, nil
+	}
+
+	current, err := toBsonMap(d.current)
+	if err != nil {
+		return nil, err
+	}
+
+	set := bson.M{}
+	unset := bson.M{}
+	diffBsonMaps(d.snapshot, current, "", set, unset)
+	delete(set, "_id")
+
+	update := bson.M{}
+	if len(set) > 0 {
+		update["$set"] = set
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	return update, nil
+}
+
+// diffBsonMaps adds the paths that differ between two bson documents to set and unset. Subdocuments
+// are compared key by key, anything else (including arrays) is set as a whole
+func diffBsonMaps(original, current bson.M, prefix string, set, unset bson.M) {
+	for key, value := range current {
+		path := prefix + key
+
+		old, ok := original[key]
+		if !ok {
+			set[path] = value
+			continue
+		}
+
+		oldSub, oldIsSub := old.(bson.M)
+		sub, isSub := value.(bson.M)
+		if oldIsSub && isSub {
+			diffBsonMaps(oldSub, sub, path+".", set, unset)
+		} else if !reflect.DeepEqual(old, value) {
+			set[path] = value
+		}
+	}
+
+	for key := range original {
+		if _, ok := current[key]; !ok {
+			unset[prefix+key] = ""
+		}
+	}
+}
+
+// toBsonMap round trips a struct through bson so that paths match what is stored in the database
+func toBsonMap(doc interface{}) (bson.M, error) {
+	data, err := bson.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	m := bson.M{}
+	err = bson.Unmarshal(data, m)
+	return m, err
+}
+
+func lookupBsonPath(m bson.M, path []string) (interface{}, bool) {
+	var value interface{} = m
+
+	for _, p := range path {
+		sub, ok := value.(bson.M)
+		if !ok {
+			return nil, false
+		}
+		if value, ok = sub[p]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
 }
 
 // Modified ...
@@ -92,15 +203,24 @@ func (d *DiffTracker) GetOriginalValue(field string) (interface{}, error) {
 // SetOriginal ...
 func (d *DiffTracker) SetOriginal(orig interface{}) {
 	d.original = reflect.Indirect(reflect.ValueOf(orig)).Interface()
+	d.snapshot, _ = toBsonMap(d.original)
 }
 
 // Clear ...
 func (d *DiffTracker) Clear() {
 	d.original = nil
+	d.snapshot = nil
 }
 
 // Compare ...
 func (d *DiffTracker) Compare(useBson bool) (bool, []string, error) {
+	if useBson {
+		return d.compare(GetBsonName)
+	}
+	return d.compare(goName)
+}
+
+func (d *DiffTracker) compare(name func(reflect.StructField) string) (bool, []string, error) {
 	defer func() {
 
 		if r := recover(); r != nil {
@@ -109,13 +229,13 @@ func (d *DiffTracker) Compare(useBson bool) (bool, []string, error) {
 		}
 	}()
 	if d.original != nil {
-		diffs, err := GetChangedFields(d.original, d.current, useBson)
+		diffs, err := changedFields(d.original, d.current, name)
 		return false, diffs, err
 	}
 	return true, []string{}, nil
 }
 
-func getFields(t reflect.Type) []string {
+func getFields(t reflect.Type, name func(reflect.StructField) string) []string {
 	fields := []string{}
 
 	if t.Kind() == reflect.Ptr {
@@ -124,7 +244,13 @@ func getFields(t reflect.Type) []string {
 
 	for i := 0; i < t.NumField(); i++ {
 		field := t.Field(i)
-		fields = append(fields, field.Name)
+
+		// Skip if not exported
+		if len(field.PkgPath) > 0 {
+			continue
+		}
+
+		fields = append(fields, name(field))
 	}
 
 	return fields
@@ -145,6 +271,13 @@ type Stringer interface {
 
 // GetChangedFields ...
 func GetChangedFields(struct1 interface{}, struct2 interface{}, useBson bool) ([]string, error) {
+	if useBson {
+		return changedFields(struct1, struct2, GetBsonName)
+	}
+	return changedFields(struct1, struct2, goName)
+}
+
+func changedFields(struct1 interface{}, struct2 interface{}, name func(reflect.StructField) string) ([]string, error) {
 
 	var diffs []string
 	val1 := reflect.ValueOf(struct1)
@@ -185,12 +318,7 @@ func GetChangedFields(struct1 interface{}, struct2 interface{}, useBson bool) ([
 			}
 		}
 
-		var fieldName string
-		if useBson {
-			fieldName = GetBsonName(field)
-		} else {
-			fieldName = field.Name
-		}
+		fieldName := name(field)
 
 		childType := field1.Type()
 		// Recurse?
@@ -211,7 +339,7 @@ func GetChangedFields(struct1 interface{}, struct2 interface{}, useBson bool) ([
 			if isNilOrInvalid(field1) && isNilOrInvalid(field2) {
 				continue
 			} else if isNilOrInvalid(field1) || isNilOrInvalid(field2) {
-				childDiffs = getFields(childType)
+				childDiffs = getFields(childType, name)
 
 			} else {
 				if _, ok := field1.Interface().(Stringer); ok {
@@ -220,7 +348,7 @@ func GetChangedFields(struct1 interface{}, struct2 interface{}, useBson bool) ([
 					}
 
 				} else {
-					childDiffs, err = GetChangedFields(field1.Interface(), field2.Interface(), useBson)
+					childDiffs, err = changedFields(field1.Interface(), field2.Interface(), name)
 
 					if err != nil {
 						return diffs, err
@@ -2,6 +2,7 @@ package bongo
 
 import (
 	. "github.com/smartystreets/goconvey/convey"
+	"gopkg.in/mgo.v2/bson"
 	"reflect"
 	"testing"
 	"time"
@@ -108,6 +109,84 @@ func TestDiffTracker(t *testing.T) {
 			sess, _ = foo1.GetDiffTracker().NewSession(false)
 			So(sess.Modified("StringVal"), ShouldEqual, true)
 		})
+
+		Convey("should build a partial update from the fields modified since reset", func() {
+			foo1 := &FooChangeTest{
+				StringVal: "foo",
+				IntVal:    1,
+				Arr:       []string{"a"},
+			}
+
+			update, err := foo1.GetDiffTracker().GetUpdate()
+			So(err, ShouldEqual, nil)
+			So(update, ShouldBeNil)
+
+			foo1.diffTracker.Reset()
+			foo1.StringVal = "bar"
+
+			update, err = foo1.diffTracker.GetUpdate()
+			So(err, ShouldEqual, nil)
+			So(update, ShouldResemble, bson.M{
+				"$set": bson.M{"stringval": "bar"},
+			})
+
+			foo1.diffTracker.Reset()
+			update, err = foo1.diffTracker.GetUpdate()
+			So(err, ShouldEqual, nil)
+			So(len(update), ShouldEqual, 0)
+		})
+
+		Convey("should build a partial update from in place changes", func() {
+			doc := &trackedDocument{
+				Tags:    []string{"a"},
+				Meta:    map[string]string{"k": "v"},
+				Address: &trackedAddress{Zip: "1"},
+			}
+			doc.GetDiffTracker().Reset()
+
+			doc.Tags[0] = "b"
+			doc.Meta["k"] = "w"
+			doc.Address.Zip = "2"
+
+			update, err := doc.GetDiffTracker().GetUpdate()
+			So(err, ShouldEqual, nil)
+			So(update, ShouldResemble, bson.M{
+				"$set": bson.M{"tags": []interface{}{"b"}, "meta.k": "w", "address.zip": "2"},
+			})
+		})
+
+		Convey("should keep camelCase bson names while updating the stored keys", func() {
+			foo1 := &FooChangeTest{StringVal: "foo"}
+			foo1.GetDiffTracker().Reset()
+			foo1.StringVal = "bar"
+
+			_, changed := foo1.diffTracker.GetModified(true)
+			So(changed, ShouldResemble, []string{"stringVal"})
+
+			update, err := foo1.diffTracker.GetUpdate()
+			So(err, ShouldEqual, nil)
+			So(update, ShouldResemble, bson.M{
+				"$set": bson.M{"stringval": "bar"},
+			})
+		})
+
+		Convey("should set a whole subdocument in a partial update if it was nil before", func() {
+			foobar := &FooBarChangeTest{
+				BarVal: "bar",
+			}
+			tracker := NewDiffTracker(foobar)
+			tracker.Reset()
+
+			foobar.FooVal = &FooChangeTest{
+				StringVal: "foo",
+			}
+
+			update, err := tracker.GetUpdate()
+			So(err, ShouldEqual, nil)
+			set := update["$set"].(bson.M)
+			So(len(set), ShouldEqual, 1)
+			So(set["fooval"], ShouldNotBeNil)
+		})
 	})
 
 }
@@ -45,3 +45,35 @@ func (d *DocumentBase) SetCreated(t time.Time) {
 func (d *DocumentBase) SetModified(t time.Time) {
 	d.ModifiedAt = t
 }
+
+// VersionedDocumentBase is a DocumentBase with a version field for optimistic concurrency control
+type VersionedDocumentBase struct {
+	DocumentBase `bson:",inline"`
+	Version      int `bson:"_v" json:"_v"`
+}
+
+// GetVersion satisfy the versioned document interface
+func (d *VersionedDocumentBase) GetVersion() int {
+	return d.Version
+}
+
+// SetVersion ...
+func (d *VersionedDocumentBase) SetVersion(v int) {
+	d.Version = v
+}
+
+// SoftDeleteDocumentBase is a DocumentBase that is only marked as deleted by Collection.Delete
+type SoftDeleteDocumentBase struct {
+	DocumentBase `bson:",inline"`
+	DeletedAt    time.Time `bson:"deletedAt,omitempty" json:"deletedAt,omitempty"`
+}
+
+// SetDeleted satisfy the soft deletable interface
+func (d *SoftDeleteDocumentBase) SetDeleted(t time.Time) {
+	d.DeletedAt = t
+}
+
+// IsDeleted ...
+func (d *SoftDeleteDocumentBase) IsDeleted() bool {
+	return !d.DeletedAt.IsZero()
+}
@@ -0,0 +1,161 @@
+package bongo
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"regexp"
+	"strings"
+
+	"gopkg.in/mgo.v2"
+)
+
+// Sentinel errors to check for with errors.Is. Every error bongo returns for these cases matches
+// one of them, and can also be unwrapped to its type with errors.As (e.g. *DocumentNotFoundError)
+var (
+	ErrNotFound               = errors.New("Document not found")
+	ErrDuplicateKey           = errors.New("Duplicate key")
+	ErrValidation             = errors.New("Validation failed")
+	ErrCascade                = errors.New("Cascade failed")
+	ErrConcurrentModification = errors.New("Concurrent modification")
+	ErrTimeout                = errors.New("Operation timed out")
+	ErrNetwork                = errors.New("Network error")
+	ErrDeleteRestricted       = errors.New("Delete restricted")
+	ErrCascadeLimit           = errors.New("Cascade limit exceeded")
+	ErrMigrationLocked        = errors.New("Migrations are locked by another process")
+	// A PaginateAfter cursor that was tampered with or issued for another collection, query or sort
+	ErrInvalidCursor       = errors.New("Invalid cursor")
+	ErrResultLimit         = errors.New("Too many results")
+	ErrEstimateUnavailable = errors.New("Count can't be estimated")
+)
+
+// DuplicateKeyError is a duplicate key error (E11000) from mongo
+type DuplicateKeyError struct {
+	// Name of the violated index, e.g. "email_1"
+	Index string
+	// The fields of the index, if known
+	Keys []string
+	Err  error
+}
+
+func (d *DuplicateKeyError) Error() string {
+	return d.Err.Error()
+}
+
+// Is ...
+func (d *DuplicateKeyError) Is(target error) bool {
+	return target == ErrDuplicateKey
+}
+
+// Unwrap ...
+func (d *DuplicateKeyError) Unwrap() error {
+	return d.Err
+}
+
+// TimeoutError is returned when an operation runs past a socket timeout, maxTimeMS or a context deadline
+type TimeoutError struct {
+	Err error
+}
+
+func (t *TimeoutError) Error() string {
+	return "Operation timed out: " + t.Err.Error()
+}
+
+// Is ...
+func (t *TimeoutError) Is(target error) bool {
+	return target == ErrTimeout
+}
+
+// Unwrap ...
+func (t *TimeoutError) Unwrap() error {
+	return t.Err
+}
+
+// NetworkError is returned when the database can't be reached or the connection was closed
+type NetworkError struct {
+	Err error
+}
+
+func (n *NetworkError) Error() string {
+	return "Network error: " + n.Err.Error()
+}
+
+// Is ...
+func (n *NetworkError) Is(target error) bool {
+	return target == ErrNetwork
+}
+
+// Unwrap ...
+func (n *NetworkError) Unwrap() error {
+	return n.Err
+}
+
+var dupIndexRegex = regexp.MustCompile(`index: (\S+) dup key`)
+
+// Mongo's error code for operations that ran past maxTimeMS
+const codeExceededTimeLimit = 50
+
+// wrapError converts errors from mgo (and context deadlines) into bongo's typed errors.
+// Anything else, including context.Canceled, is returned as it is
+func wrapError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if err == mgo.ErrNotFound {
+		return &DocumentNotFoundError{}
+	}
+
+	if mgo.IsDup(err) {
+		dup := &DuplicateKeyError{Err: err}
+		if match := dupIndexRegex.FindStringSubmatch(err.Error()); match != nil {
+			dup.Index = match[1]
+		}
+		return dup
+	}
+
+	if isTimeout(err) {
+		return &TimeoutError{err}
+	}
+
+	if isNetworkError(err) {
+		return &NetworkError{err}
+	}
+
+	return err
+}
+
+func isTimeout(err error) bool {
+	if err == context.DeadlineExceeded {
+		return true
+	}
+
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		return true
+	}
+
+	switch e := err.(type) {
+	case *mgo.QueryError:
+		return e.Code == codeExceededTimeLimit
+	case *mgo.LastError:
+		return e.Code == codeExceededTimeLimit
+	}
+
+	return strings.Contains(err.Error(), "i/o timeout")
+}
+
+func isNetworkError(err error) bool {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return true
+	}
+
+	if _, ok := err.(net.Error); ok {
+		return true
+	}
+
+	msg := err.Error()
+	return strings.Contains(msg, "no reachable servers") ||
+		strings.Contains(msg, "Closed explicitly") ||
+		strings.Contains(msg, "connection reset")
+}
@@ -0,0 +1,85 @@
+package bongo
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestErrors(t *testing.T) {
+	Convey("wrapError", t, func() {
+		Convey("should convert mgo's not found", func() {
+			err := wrapError(mgo.ErrNotFound)
+			So(errors.Is(err, ErrNotFound), ShouldEqual, true)
+
+			var notFound *DocumentNotFoundError
+			So(errors.As(err, &notFound), ShouldEqual, true)
+		})
+
+		Convey("should convert duplicate key errors", func() {
+			err := wrapError(&mgo.LastError{
+				Code: 11000,
+				Err:  `E11000 duplicate key error collection: bongotest.docs index: name_1 dup key: { : "foo" }`,
+			})
+			So(errors.Is(err, ErrDuplicateKey), ShouldEqual, true)
+
+			var dup *DuplicateKeyError
+			So(errors.As(err, &dup), ShouldEqual, true)
+			So(dup.Index, ShouldEqual, "name_1")
+		})
+
+		Convey("should convert timeouts and network errors", func() {
+			err := wrapError(&mgo.QueryError{Code: 50, Message: "operation exceeded time limit"})
+			So(errors.Is(err, ErrTimeout), ShouldEqual, true)
+
+			err = wrapError(context.DeadlineExceeded)
+			So(errors.Is(err, ErrTimeout), ShouldEqual, true)
+			So(errors.Is(err, context.DeadlineExceeded), ShouldEqual, true)
+
+			err = wrapError(io.EOF)
+			So(errors.Is(err, ErrNetwork), ShouldEqual, true)
+			So(errors.Is(err, io.EOF), ShouldEqual, true)
+
+			err = wrapError(errors.New("no reachable servers"))
+			So(errors.Is(err, ErrNetwork), ShouldEqual, true)
+		})
+
+		Convey("should leave other errors alone", func() {
+			So(wrapError(nil), ShouldEqual, nil)
+			So(wrapError(context.Canceled), ShouldEqual, context.Canceled)
+
+			err := errors.New("foo")
+			So(wrapError(err), ShouldEqual, err)
+		})
+	})
+
+	Convey("typed errors", t, func() {
+		Convey("should match their sentinels", func() {
+			So(errors.Is(&ConcurrentModificationError{ID: bson.NewObjectId()}, ErrConcurrentModification), ShouldEqual, true)
+			So(errors.Is(&ValidationError{}, ErrValidation), ShouldEqual, true)
+			So(errors.Is(&CascadeError{}, ErrCascade), ShouldEqual, true)
+			So(errors.Is(DocumentNotFoundError{}, ErrNotFound), ShouldEqual, true)
+		})
+
+		Convey("should unwrap the errors they collect", func() {
+			dup := &DuplicateKeyError{Index: "name_1", Err: errors.New("E11000")}
+			fe := NewFieldError("name", "Name", "unique", nil, "name must be unique")
+			fe.Err = dup
+			var err error = &ValidationError{[]error{fe}}
+
+			So(errors.Is(err, ErrDuplicateKey), ShouldEqual, true)
+
+			var found *DuplicateKeyError
+			So(errors.As(err, &found), ShouldEqual, true)
+			So(found.Index, ShouldEqual, "name_1")
+
+			err = &CascadeError{[]*CascadeFailure{&CascadeFailure{Err: wrapError(io.EOF)}}}
+			So(errors.Is(err, ErrNetwork), ShouldEqual, true)
+		})
+	})
+}
@@ -0,0 +1,202 @@
+package bongo
+
+import (
+	"context"
+	"errors"
+	"reflect"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// Cascade operations to explain
+const (
+	CascadeOpSave   = iota
+	CascadeOpDelete = iota
+)
+
+// CascadeOperation is one step of a cascade
+type CascadeOperation struct {
+	// The collection of the related documents
+	Collection string `json:"collection"`
+	// "update" for an UpdateAll, "restrict" for the check of an OnDeleteRestrict config,
+	// "nest" for nested saves and "delete" for the deletes of an OnDeleteCascade config
+	Kind        string `json:"kind"`
+	Description string `json:"description"`
+	Selector    bson.M `json:"selector"`
+	// The update document, for "update" operations
+	Update bson.M `json:"update,omitempty"`
+	// Number of documents the selector currently matches (ExplainCascade only). For "restrict",
+	// the delete is refused if this isn't zero
+	Matched int `json:"matched"`
+	// The cascades of each related document, for "nest" and "delete" operations
+	Nested []*CascadeExplanation `json:"nested,omitempty"`
+}
+
+// CascadeExplanation lists what a cascade of a document would do
+type CascadeExplanation struct {
+	Collection string              `json:"collection"`
+	ID         bson.ObjectId       `json:"id"`
+	Operations []*CascadeOperation `json:"operations"`
+}
+
+// ExplainCascade returns the operations CascadeSave (op CascadeOpSave) or a Delete (CascadeOpDelete)
+// would run for the document, with the number of documents each one currently matches. Nothing is
+// written. Nested cascades are explained with the related documents as they are now, before the
+// cascade would update them
+func ExplainCascade(collection *Collection, doc Document, op int) (*CascadeExplanation, error) {
+	if op != CascadeOpSave && op != CascadeOpDelete {
+		return nil, errors.New("Invalid cascade operation")
+	}
+	return explainCascade(context.Background(), collection, doc, op)
+}
+
+func explainCascade(ctx context.Context, collection *Collection, doc Document, op int) (*CascadeExplanation, error) {
+	ctx, err := enterCascade(ctx, collection, doc.GetID())
+	if err != nil {
+		return nil, err
+	}
+
+	explanation := &CascadeExplanation{
+		Collection: collection.Name,
+		ID:         doc.GetID(),
+		Operations: []*CascadeOperation{},
+	}
+
+	configs, err := cascadeConfigs(collection, doc)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, conf := range configs {
+		if len(conf.ReferenceQuery) == 0 {
+			conf.ReferenceQuery = []*ReferenceField{&ReferenceField{"_id", doc.GetID()}}
+		}
+
+		var ops []*CascadeOperation
+		if op == CascadeOpSave {
+			ops, err = explainSave(ctx, conf)
+		} else {
+			ops, err = explainDelete(ctx, conf)
+		}
+		if err != nil {
+			return nil, err
+		}
+		explanation.Operations = append(explanation.Operations, ops...)
+	}
+
+	return explanation, nil
+}
+
+func explainSave(ctx context.Context, conf *CascadeConfig) ([]*CascadeOperation, error) {
+	ops, err := planCascadeSave(conf)
+	if err != nil {
+		return nil, err
+	}
+	if err := countMatches(conf, ops); err != nil {
+		return nil, err
+	}
+
+	if conf.Nest && conf.Instance != nil {
+		nest := &CascadeOperation{
+			Collection:  conf.Collection.Name,
+			Kind:        "nest",
+			Description: "cascade related documents",
+			Selector:    conf.Query,
+		}
+		if nest.Nested, err = explainRelated(ctx, conf, CascadeOpSave); err != nil {
+			return nil, err
+		}
+		nest.Matched = len(nest.Nested)
+		ops = append(ops, nest)
+	}
+
+	return ops, nil
+}
+
+func explainDelete(ctx context.Context, conf *CascadeConfig) ([]*CascadeOperation, error) {
+	switch conf.OnDelete {
+	case OnDeleteRestrict:
+		count, err := conf.Collection.Find(conf.Query).Query.Count()
+		if err != nil {
+			return nil, wrapError(err)
+		}
+
+		return []*CascadeOperation{&CascadeOperation{
+			Collection:  conf.Collection.Name,
+			Kind:        "restrict",
+			Description: "refuse to delete while related documents exist",
+			Selector:    conf.Query,
+			Matched:     count,
+		}}, nil
+	case OnDeleteCascade:
+		if conf.Instance == nil {
+			return nil, errors.New("OnDeleteCascade needs an Instance to load the related documents into")
+		}
+
+		del := &CascadeOperation{
+			Collection:  conf.Collection.Name,
+			Kind:        "delete",
+			Description: "delete related documents",
+			Selector:    conf.Query,
+		}
+
+		var err error
+		if del.Nested, err = explainRelated(ctx, conf, CascadeOpDelete); err != nil {
+			return nil, err
+		}
+		del.Matched = len(del.Nested)
+		return []*CascadeOperation{del}, nil
+	}
+
+	ops, err := planCascadeDelete(conf)
+	if err != nil {
+		return nil, err
+	}
+	return ops, countMatches(conf, ops)
+}
+
+// explainRelated explains the cascades of the related documents of the config, skipping the ones
+// that are already part of the explanation
+func explainRelated(ctx context.Context, conf *CascadeConfig, op int) ([]*CascadeExplanation, error) {
+	explanations := []*CascadeExplanation{}
+	docType := reflect.TypeOf(conf.Instance).Elem()
+
+	results := conf.Collection.FindCtx(ctx, conf.Query)
+	defer results.Free()
+
+	for {
+		doc := reflect.New(docType).Interface().(Document)
+		if !results.Next(doc) {
+			break
+		}
+
+		nested, ok, err := descendCascade(ctx, conf.Collection, doc.GetID())
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		explanation, err := explainCascade(nested, conf.Collection, doc, op)
+		if err != nil {
+			return nil, err
+		}
+		explanations = append(explanations, explanation)
+	}
+
+	return explanations, results.Error
+}
+
+func countMatches(conf *CascadeConfig, ops []*CascadeOperation) error {
+	col := conf.Collection.Collection()
+
+	for _, op := range ops {
+		count, err := col.Find(op.Selector).Count()
+		if err != nil {
+			return wrapError(err)
+		}
+		op.Matched = count
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package bongo
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"gopkg.in/mgo.v2/bson"
+	"testing"
+)
+
+func TestExplainCascade(t *testing.T) {
+	Convey("planCascadeSave", t, func() {
+		conf := &CascadeConfig{
+			Collection:     (&Connection{}).Collection("parents"),
+			RelType:        RelMany,
+			ThroughProp:    "children",
+			Data:           bson.M{"name": "Jo"},
+			Query:          bson.M{"_id": "new"},
+			OldQuery:       bson.M{"_id": "old"},
+			ReferenceQuery: []*ReferenceField{&ReferenceField{"_id", "child"}},
+			SortKey:        "-name",
+		}
+
+		ops, err := planCascadeSave(conf)
+		So(err, ShouldEqual, nil)
+		So(len(ops), ShouldEqual, 4)
+		So(ops[0].Selector, ShouldResemble, bson.M{"_id": "old"})
+		So(ops[0].Update, ShouldResemble, bson.M{"$pull": bson.M{"children": bson.M{"_id": "child"}}})
+		So(ops[1].Update, ShouldResemble, bson.M{"$set": bson.M{"children.$": bson.M{"name": "Jo"}}})
+		So(ops[2].Description, ShouldEqual, "push where missing")
+		So(ops[3].Description, ShouldEqual, "sort")
+
+		conf.RemoveOnly = true
+		ops, _ = planCascadeSave(conf)
+		So(len(ops), ShouldEqual, 1)
+
+		conf.RelType = -1
+		_, err = planCascadeSave(conf)
+		So(err, ShouldNotEqual, nil)
+	})
+
+	Convey("ExplainCascade", t, func() {
+		connection := getConnection()
+		defer connection.Session.Close()
+		connection.Session.DB("bongotest").DropDatabase()
+
+		parents := connection.Collection("parents")
+		parent := &Parent{}
+		So(parents.Save(parent), ShouldEqual, nil)
+
+		child := &Child{ParentID: parent.ID, Name: "Jo"}
+		child.ID = bson.NewObjectId()
+
+		Convey("should list the operations of a save without writing", func() {
+			explanation, err := ExplainCascade(connection.Collection("children"), child, CascadeOpSave)
+			So(err, ShouldEqual, nil)
+			So(explanation.ID, ShouldEqual, child.ID)
+
+			// child (RelOne), children (in place + push) and childProp (RelOne)
+			So(len(explanation.Operations), ShouldEqual, 4)
+			So(explanation.Operations[0].Matched, ShouldEqual, 1)
+			So(explanation.Operations[1].Matched, ShouldEqual, 0)
+			So(explanation.Operations[2].Matched, ShouldEqual, 1)
+
+			saved := &Parent{}
+			parents.FindByID(parent.ID, saved)
+			So(len(saved.Children), ShouldEqual, 0)
+		})
+
+		Convey("should explain nested deletes", func() {
+			team := &deleteTeam{onDelete: OnDeleteCascade}
+			So(connection.Collection("teams").Save(team), ShouldEqual, nil)
+			So(connection.Collection("roster").Collection().Insert(&rosterEntry{
+				DocumentBase: DocumentBase{ID: bson.NewObjectId()},
+				TeamID:       team.ID,
+			}), ShouldEqual, nil)
+
+			explanation, err := ExplainCascade(connection.Collection("teams"), team, CascadeOpDelete)
+			So(err, ShouldEqual, nil)
+			So(len(explanation.Operations), ShouldEqual, 1)
+			So(explanation.Operations[0].Kind, ShouldEqual, "delete")
+			So(explanation.Operations[0].Matched, ShouldEqual, 1)
+			So(len(explanation.Operations[0].Nested[0].Operations), ShouldEqual, 1)
+
+			count, _ := connection.Collection("roster").Collection().Count()
+			So(count, ShouldEqual, 1)
+		})
+	})
+}
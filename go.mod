@@ -0,0 +1,3 @@
+module github.com/maxwellhealth/bongo
+
+go 1.20
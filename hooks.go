@@ -0,0 +1,77 @@
+package bongo
+
+import (
+	"context"
+	"time"
+
+	"gopkg.in/mgo.v2"
+)
+
+// The hooks taking a context win over the plain ones if a document implements both
+
+func runValidate(ctx context.Context, c *Collection, doc interface{}) []error {
+	if validator, ok := doc.(ValidateCtxHook); ok {
+		return validator.ValidateCtx(ctx, c)
+	}
+	if validator, ok := doc.(ValidateHook); ok {
+		return validator.Validate(c)
+	}
+	return nil
+}
+
+func runBeforeSave(ctx context.Context, c *Collection, doc interface{}) error {
+	if hook, ok := doc.(BeforeSaveCtxHook); ok {
+		return hook.BeforeSaveCtx(ctx, c)
+	}
+	if hook, ok := doc.(BeforeSaveHook); ok {
+		return hook.BeforeSave(c)
+	}
+	return nil
+}
+
+func runAfterSave(ctx context.Context, c *Collection, doc interface{}) error {
+	if hook, ok := doc.(AfterSaveCtxHook); ok {
+		return hook.AfterSaveCtx(ctx, c)
+	}
+	if hook, ok := doc.(AfterSaveHook); ok {
+		return hook.AfterSave(c)
+	}
+	return nil
+}
+
+func runBeforeDelete(ctx context.Context, c *Collection, doc interface{}) error {
+	if hook, ok := doc.(BeforeDeleteCtxHook); ok {
+		return hook.BeforeDeleteCtx(ctx, c)
+	}
+	if hook, ok := doc.(BeforeDeleteHook); ok {
+		return hook.BeforeDelete(c)
+	}
+	return nil
+}
+
+func runAfterDelete(ctx context.Context, c *Collection, doc interface{}) error {
+	if hook, ok := doc.(AfterDeleteCtxHook); ok {
+		return hook.AfterDeleteCtx(ctx, c)
+	}
+	if hook, ok := doc.(AfterDeleteHook); ok {
+		return hook.AfterDelete(c)
+	}
+	return nil
+}
+
+func runAfterFind(ctx context.Context, c *Collection, doc interface{}) error {
+	if hook, ok := doc.(AfterFindCtxHook); ok {
+		return hook.AfterFindCtx(ctx, c)
+	}
+	if hook, ok := doc.(AfterFindHook); ok {
+		return hook.AfterFind(c)
+	}
+	return nil
+}
+
+// setMaxTime maps the context's deadline to the query's maxTimeMS
+func setMaxTime(ctx context.Context, q *mgo.Query) {
+	if deadline, ok := ctx.Deadline(); ok {
+		q.SetMaxTime(time.Until(deadline))
+	}
+}
@@ -0,0 +1,317 @@
+package bongo
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/mgo.v2"
+)
+
+// IndexedDocument declares indexes in addition to the ones from its bongo struct tags
+type IndexedDocument interface {
+	GetIndexes() []mgo.Index
+}
+
+// CollectionNamer tells Connection.EnsureIndexes which collection a model is stored in
+type CollectionNamer interface {
+	CollectionName() string
+}
+
+// IndexDrift is a declared index that exists with different options
+type IndexDrift struct {
+	Declared mgo.Index
+	Existing mgo.Index
+}
+
+// IndexReport is the result of syncing the declared indexes of a model
+type IndexReport struct {
+	Collection string
+	// Declared indexes that were missing and have been created
+	Created []mgo.Index
+	// Declared indexes that already exist as declared
+	Unchanged []mgo.Index
+	// Declared indexes that exist with different options. These are not changed
+	Drifted []*IndexDrift
+	// Indexes that exist but aren't declared (anymore)
+	Undeclared []mgo.Index
+	// Undeclared indexes that have been dropped
+	Dropped []mgo.Index
+}
+
+type indexOptions struct {
+	dropUndeclared bool
+	dryRun         bool
+}
+
+// IndexOption changes how EnsureIndexes syncs indexes
+type IndexOption func(*indexOptions)
+
+// DropUndeclaredIndexes drops indexes that exist but aren't declared (anymore)
+func DropUndeclaredIndexes() IndexOption {
+	return func(o *indexOptions) {
+		o.dropUndeclared = true
+	}
+}
+
+// IndexDryRun only reports what would be created or dropped
+func IndexDryRun() IndexOption {
+	return func(o *indexOptions) {
+		o.dryRun = true
+	}
+}
+
+// DeclaredIndexes returns the indexes a document declares with GetIndexes and its bongo struct tags:
+//
+//	Email     string    `bongo:"unique"`          // unique index
+//	LastName  string    `bongo:"index=name"`      // compound index on lastName + firstName
+//	FirstName string    `bongo:"index=name"`
+//	TeamID    string    `bongo:"unique=number"`   // compound unique index on teamId + number
+//	Number    int       `bongo:"unique=number"`
+//	Nickname  string    `bongo:"index,sparse"`    // sparse index
+//	ExpiresAt time.Time `bongo:"ttl=3600"`        // TTL index, documents expire an hour after ExpiresAt
+//	Bio       string    `bongo:"text"`            // all text fields share one text index
+//
+// Fields of nested structs and slices are indexed by their bson path
+func DeclaredIndexes(doc interface{}) []mgo.Index {
+	b := &indexBuilder{indexes: map[string]*mgo.Index{}}
+	b.collect(reflect.TypeOf(doc), "", map[reflect.Type]bool{})
+
+	indexes := []mgo.Index{}
+	if indexed, ok := doc.(IndexedDocument); ok {
+		indexes = append(indexes, indexed.GetIndexes()...)
+	}
+
+	for _, name := range b.order {
+		indexes = mergeIndex(indexes, *b.indexes[name])
+	}
+	return indexes
+}
+
+// mergeIndex adds an index, unless there already is one with the same key. If there is,
+// their options are combined (e.g. `bongo:"index,unique"` is a single unique index)
+func mergeIndex(indexes []mgo.Index, index mgo.Index) []mgo.Index {
+	for i, existing := range indexes {
+		if indexKey(existing) == indexKey(index) {
+			indexes[i].Unique = existing.Unique || index.Unique
+			indexes[i].Sparse = existing.Sparse || index.Sparse
+			if existing.ExpireAfter == 0 {
+				indexes[i].ExpireAfter = index.ExpireAfter
+			}
+			return indexes
+		}
+	}
+	return append(indexes, index)
+}
+
+// indexKey identifies an index by its key. The fields of text indexes aren't ordered
+func indexKey(index mgo.Index) string {
+	key := append([]string{}, index.Key...)
+
+	if len(key) > 0 && strings.HasPrefix(key[0], "$text:") {
+		sort.Strings(key)
+	}
+	return strings.Join(key, ",")
+}
+
+type indexBuilder struct {
+	order   []string
+	indexes map[string]*mgo.Index
+}
+
+func (b *indexBuilder) get(name string) *mgo.Index {
+	if index, ok := b.indexes[name]; ok {
+		return index
+	}
+
+	index := &mgo.Index{}
+	b.indexes[name] = index
+	b.order = append(b.order, name)
+	return index
+}
+
+func (b *indexBuilder) collect(t reflect.Type, prefix string, seen map[reflect.Type]bool) {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || t == timeType || seen[t] {
+		return
+	}
+
+	// Don't follow recursive types forever
+	seen[t] = true
+	defer delete(seen, t)
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := bsonName(field)
+
+		if len(field.PkgPath) > 0 || name == "-" {
+			continue
+		}
+
+		path := prefix
+		if !hasBsonFlag(field, "inline") {
+			path = joinPath(prefix, name)
+		}
+
+		b.addField(path, parseTag(field))
+		b.collect(field.Type, path, seen)
+	}
+}
+
+// addField adds the indexes declared by one field's tag options
+func (b *indexBuilder) addField(path string, opts []tagOption) {
+	indexes := []*mgo.Index{}
+
+	for _, opt := range opts {
+		var index *mgo.Index
+
+		switch opt.Key {
+		case "index", "unique":
+			// Fields without a group get an index of their own
+			group := opt.Value
+			if len(group) == 0 {
+				group = "@" + path
+			}
+			index = b.get(opt.Key + ":" + group)
+			index.Unique = opt.Key == "unique"
+			index.Key = append(index.Key, path)
+		case "text":
+			index = b.get("text")
+			index.Key = append(index.Key, "$text:"+path)
+		default:
+			continue
+		}
+		indexes = append(indexes, index)
+	}
+
+	for _, opt := range opts {
+		switch opt.Key {
+		case "sparse":
+			for _, index := range indexes {
+				index.Sparse = true
+			}
+		case "ttl":
+			seconds, err := strconv.Atoi(opt.Value)
+			if err != nil {
+				continue
+			}
+
+			if len(indexes) == 0 {
+				index := b.get("index:@" + path)
+				index.Key = []string{path}
+				indexes = append(indexes, index)
+			}
+			for _, index := range indexes {
+				index.ExpireAfter = time.Duration(seconds) * time.Second
+			}
+		}
+	}
+}
+
+// EnsureIndexes creates the indexes the document declares (see DeclaredIndexes) that don't
+// exist yet, and reports the ones that exist with different options or aren't declared
+func (c *Collection) EnsureIndexes(doc interface{}, opts ...IndexOption) (*IndexReport, error) {
+	options := &indexOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	sess := c.Connection.Session.Clone()
+	defer sess.Close()
+	col := c.CollectionOnSession(sess)
+
+	existing, err := col.Indexes()
+	if err != nil && !isNamespaceNotFound(err) {
+		return nil, wrapError(err)
+	}
+
+	report := &IndexReport{Collection: c.Name}
+	declared := DeclaredIndexes(doc)
+	matched := map[string]bool{}
+
+	for _, index := range declared {
+		var found *mgo.Index
+		for i := range existing {
+			if indexKey(existing[i]) == indexKey(index) {
+				found = &existing[i]
+			}
+		}
+
+		if found == nil {
+			if !options.dryRun {
+				if err := col.EnsureIndex(index); err != nil {
+					return report, wrapError(err)
+				}
+			}
+			report.Created = append(report.Created, index)
+			continue
+		}
+
+		matched[found.Name] = true
+		if found.Unique != index.Unique || found.Sparse != index.Sparse || found.ExpireAfter != index.ExpireAfter {
+			report.Drifted = append(report.Drifted, &IndexDrift{Declared: index, Existing: *found})
+		} else {
+			report.Unchanged = append(report.Unchanged, index)
+		}
+	}
+
+	for _, index := range existing {
+		if matched[index.Name] || index.Name == "_id_" {
+			continue
+		}
+		report.Undeclared = append(report.Undeclared, index)
+
+		if options.dropUndeclared {
+			if !options.dryRun {
+				if err := col.DropIndexName(index.Name); err != nil {
+					return report, wrapError(err)
+				}
+			}
+			report.Dropped = append(report.Dropped, index)
+		}
+	}
+
+	return report, nil
+}
+
+// EnsureIndexes syncs the declared indexes of each model (see Collection.EnsureIndexes).
+// The models must implement CollectionNamer
+func (m *Connection) EnsureIndexes(models ...interface{}) ([]*IndexReport, error) {
+	return m.EnsureIndexesWith(nil, models...)
+}
+
+// EnsureIndexesWith is EnsureIndexes with options, e.g. DropUndeclaredIndexes()
+func (m *Connection) EnsureIndexesWith(opts []IndexOption, models ...interface{}) ([]*IndexReport, error) {
+	reports := []*IndexReport{}
+
+	for _, model := range models {
+		namer, ok := model.(CollectionNamer)
+		if !ok {
+			return reports, fmt.Errorf("%T must implement CollectionName() to ensure its indexes", model)
+		}
+
+		report, err := m.Collection(namer.CollectionName()).EnsureIndexes(model, opts...)
+		if report != nil {
+			reports = append(reports, report)
+		}
+		if err != nil {
+			return reports, err
+		}
+	}
+	return reports, nil
+}
+
+// Mongo's error code for a collection (or database) that doesn't exist
+const codeNamespaceNotFound = 26
+
+func isNamespaceNotFound(err error) bool {
+	if qErr, ok := err.(*mgo.QueryError); ok {
+		return qErr.Code == codeNamespaceNotFound
+	}
+	return strings.Contains(err.Error(), "ns does not exist")
+}
@@ -0,0 +1,99 @@
+package bongo
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"gopkg.in/mgo.v2"
+	"testing"
+	"time"
+)
+
+type indexedAddress struct {
+	Zip string `bongo:"index"`
+}
+
+type indexedDocument struct {
+	DocumentBase `bson:",inline"`
+	Email        string           `bongo:"unique,sparse"`
+	LastName     string           `bson:"lastName" bongo:"index=name"`
+	FirstName    string           `bson:"firstName" bongo:"index=name"`
+	ExpiresAt    time.Time        `bson:"expiresAt" bongo:"ttl=3600"`
+	Bio          string           `bongo:"text"`
+	Notes        string           `bongo:"text"`
+	Addresses    []indexedAddress `bson:"addresses"`
+	Age          int
+}
+
+func (i *indexedDocument) GetIndexes() []mgo.Index {
+	return []mgo.Index{
+		mgo.Index{Key: []string{"-age"}},
+	}
+}
+
+func (i *indexedDocument) CollectionName() string {
+	return "indexed"
+}
+
+func TestIndexes(t *testing.T) {
+	Convey("DeclaredIndexes", t, func() {
+		indexes := DeclaredIndexes(&indexedDocument{})
+
+		So(len(indexes), ShouldEqual, 6)
+		So(indexes[0].Key, ShouldResemble, []string{"-age"})
+		So(indexes[1].Key, ShouldResemble, []string{"email"})
+		So(indexes[1].Unique, ShouldEqual, true)
+		So(indexes[1].Sparse, ShouldEqual, true)
+		So(indexes[2].Key, ShouldResemble, []string{"lastName", "firstName"})
+		So(indexes[3].Key, ShouldResemble, []string{"expiresAt"})
+		So(indexes[3].ExpireAfter, ShouldEqual, time.Hour)
+		So(indexes[4].Key, ShouldResemble, []string{"$text:bio", "$text:notes"})
+		So(indexes[5].Key, ShouldResemble, []string{"addresses.zip"})
+	})
+
+	Convey("EnsureIndexes", t, func() {
+		conn := getConnection()
+		defer conn.Session.Close()
+
+		Convey("should create missing indexes and report the rest", func() {
+			err := conn.Collection("indexed").Collection().EnsureIndex(mgo.Index{
+				Key: []string{"legacy"},
+			})
+			So(err, ShouldEqual, nil)
+			err = conn.Collection("indexed").Collection().EnsureIndex(mgo.Index{
+				Key: []string{"email"},
+			})
+			So(err, ShouldEqual, nil)
+
+			reports, err := conn.EnsureIndexes(&indexedDocument{})
+			So(err, ShouldEqual, nil)
+			So(len(reports), ShouldEqual, 1)
+
+			report := reports[0]
+			So(report.Collection, ShouldEqual, "indexed")
+			So(len(report.Created), ShouldEqual, 5)
+			So(len(report.Drifted), ShouldEqual, 1)
+			So(report.Drifted[0].Existing.Name, ShouldEqual, "email_1")
+			So(len(report.Undeclared), ShouldEqual, 1)
+			So(report.Undeclared[0].Name, ShouldEqual, "legacy_1")
+			So(len(report.Dropped), ShouldEqual, 0)
+
+			report, err = conn.Collection("indexed").EnsureIndexes(&indexedDocument{}, DropUndeclaredIndexes())
+			So(err, ShouldEqual, nil)
+			So(len(report.Created), ShouldEqual, 0)
+			So(len(report.Unchanged), ShouldEqual, 5)
+			So(len(report.Dropped), ShouldEqual, 1)
+
+			indexes, err := conn.Collection("indexed").Collection().Indexes()
+			So(err, ShouldEqual, nil)
+			So(len(indexes), ShouldEqual, 7)
+		})
+
+		Convey("should require a collection name for models", func() {
+			_, err := conn.EnsureIndexes(&noHookDocument{})
+			So(err, ShouldNotEqual, nil)
+		})
+
+		Reset(func() {
+			conn.Session.DB("bongotest").DropDatabase()
+		})
+	})
+}
@@ -11,16 +11,28 @@ type Connection struct {
 	DialInfo *mgo.DialInfo
 	Session  *mgo.Session
 	Context  *Context
+	// Default for Collection.SyncCascade on collections created from this connection
+	SyncCascade bool
+	// Limits for nested cascades (Nest and OnDeleteCascade). Zero means DefaultCascadeMaxDepth
+	// and DefaultCascadeMaxDocuments
+	CascadeMaxDepth     int
+	CascadeMaxDocuments int
+	// Notified of every cascade, see CascadeObserver
+	CascadeObserver CascadeObserver
+	// Key that PaginateAfter cursors are signed with. Set it when cursors need to work across
+	// processes; if empty a random key is used for the life of the process
+	CursorSecret []byte
+	// Most documents ResultSet.All loads. Zero means DefaultMaxResults, negative means no limit
+	MaxResults int
+
+	softDelete map[string]bool
 }
 
 // Connect creates a new connection and run Connect()
 func Connect(info *mgo.DialInfo) (*Connection, error) {
-	set := make(map[string]interface{})
 	conn := &Connection{
 		DialInfo: info,
-		Context: &Context{
-			set: set,
-		},
+		Context:  NewContext(),
 	}
 	err := conn.Connect()
 	return conn, err
@@ -58,8 +70,30 @@ func (m *Connection) Connect() (err error) {
 func (m *Connection) Collection(name string) *Collection {
 	// Just create a new instance - it's cheap and only has name
 	return &Collection{
-		Connection: m,
-		Context:    m.Context,
-		Name:       name,
+		Connection:  m,
+		Context:     m.Context,
+		Name:        name,
+		SyncCascade: m.SyncCascade,
+		SoftDelete:  m.softDelete[name],
+	}
+}
+
+// WithContext returns a shallow copy of the connection whose context is an overlay of this
+// connection's context with the given values (see Context.Overlay). Use it for request scoped values
+func (m *Connection) WithContext(values map[string]interface{}) *Connection {
+	conn := *m
+	conn.Context = m.Context.Overlay(values)
+	return &conn
+}
+
+// EnableSoftDelete makes finds on the named collections exclude soft deleted documents. Call it
+// once at startup, it is not safe to use concurrently with Collection()
+func (m *Connection) EnableSoftDelete(names ...string) {
+	if m.softDelete == nil {
+		m.softDelete = make(map[string]bool)
+	}
+
+	for _, name := range names {
+		m.softDelete[name] = true
 	}
 }
@@ -0,0 +1,378 @@
+package bongo
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// MigrationsCollection stores which migrations have been applied, and the migration lock
+const MigrationsCollection = "_bongo_migrations"
+
+// The _id of the lock document in the migrations collection
+const migrationLockID = "_lock"
+
+// Migration is a single, reversible change to the documents of a collection
+type Migration struct {
+	// Migrations run ordered by ID, so prefix it with a timestamp, e.g. "20160102150405_split_names"
+	ID          string
+	Description string
+	// The collection passed to Up and Down. Use its Connection to work on other collections
+	Collection string
+	Up         func(*Collection) error
+	// Optional, but migrations without it can't be rolled back
+	Down func(*Collection) error
+}
+
+// MigrationStatus tells if a migration has been applied
+type MigrationStatus struct {
+	ID string
+	// nil if the migration was applied but isn't registered (anymore)
+	Migration *Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+type migrationRecord struct {
+	ID          string    `bson:"_id"`
+	Description string    `bson:"description"`
+	AppliedAt   time.Time `bson:"appliedAt"`
+}
+
+type migrationLock struct {
+	ID       string    `bson:"_id"`
+	Owner    string    `bson:"owner"`
+	LockedAt time.Time `bson:"lockedAt"`
+}
+
+// Migrator runs registered migrations, recording them in the MigrationsCollection. A lock document
+// in the same collection keeps two instances from migrating at once
+type Migrator struct {
+	Connection *Connection
+	// Locks that haven't been refreshed for this long are considered abandoned (e.g. the process
+	// crashed) and are taken over. The lock is refreshed every LockTimeout/3 while migrations run
+	LockTimeout time.Duration
+	// Only report which migrations would run, without running or recording them
+	DryRun bool
+
+	migrations []*Migration
+	owner      string
+}
+
+// NewMigrator ...
+func NewMigrator(conn *Connection) *Migrator {
+	host, _ := os.Hostname()
+
+	return &Migrator{
+		Connection:  conn,
+		LockTimeout: 10 * time.Minute,
+		owner:       fmt.Sprintf("%s:%d", host, os.Getpid()),
+	}
+}
+
+// Register adds migrations. IDs must be unique
+func (m *Migrator) Register(migrations ...*Migration) error {
+	for _, migration := range migrations {
+		if len(migration.ID) == 0 || migration.ID == migrationLockID {
+			return fmt.Errorf("Invalid migration ID %q", migration.ID)
+		}
+		if migration.Up == nil {
+			return fmt.Errorf("Migration %s has no Up function", migration.ID)
+		}
+		if m.find(migration.ID) != nil {
+			return fmt.Errorf("Migration %s is already registered", migration.ID)
+		}
+		m.migrations = append(m.migrations, migration)
+	}
+
+	sort.Slice(m.migrations, func(i, j int) bool {
+		return m.migrations[i].ID < m.migrations[j].ID
+	})
+	return nil
+}
+
+// Migrations returns the registered migrations in the order they run
+func (m *Migrator) Migrations() []*Migration {
+	return append([]*Migration{}, m.migrations...)
+}
+
+func (m *Migrator) find(id string) *Migration {
+	for _, migration := range m.migrations {
+		if migration.ID == id {
+			return migration
+		}
+	}
+	return nil
+}
+
+// Status lists the registered migrations in order, followed by any migrations that have been
+// applied but aren't registered
+func (m *Migrator) Status() ([]*MigrationStatus, error) {
+	sess := m.Connection.Session.Clone()
+	defer sess.Close()
+
+	records, err := m.applied(m.collection(sess))
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := []*MigrationStatus{}
+	for _, migration := range m.migrations {
+		status := &MigrationStatus{ID: migration.ID, Migration: migration}
+		if record, ok := records[migration.ID]; ok {
+			status.Applied = true
+			status.AppliedAt = record.AppliedAt
+		}
+		statuses = append(statuses, status)
+	}
+
+	unknown := []*MigrationStatus{}
+	for id, record := range records {
+		if m.find(id) == nil {
+			unknown = append(unknown, &MigrationStatus{ID: id, Applied: true, AppliedAt: record.AppliedAt})
+		}
+	}
+	sort.Slice(unknown, func(i, j int) bool {
+		return unknown[i].ID < unknown[j].ID
+	})
+
+	return append(statuses, unknown...), nil
+}
+
+// Up runs every pending migration in order, and returns the ones that were run (or would be,
+// for a dry run). It stops at the first failing migration
+func (m *Migrator) Up() ([]*Migration, error) {
+	run := []*Migration{}
+
+	err := m.locked(func(col *mgo.Collection, held func() error) error {
+		records, err := m.applied(col)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := records[migration.ID]; ok {
+				continue
+			}
+			if err := held(); err != nil {
+				return err
+			}
+
+			if !m.DryRun {
+				if err := migration.Up(m.Connection.Collection(migration.Collection)); err != nil {
+					return fmt.Errorf("Migration %s failed: %s", migration.ID, err)
+				}
+
+				err = col.Insert(&migrationRecord{
+					ID:          migration.ID,
+					Description: migration.Description,
+					AppliedAt:   time.Now(),
+				})
+				if err != nil {
+					return wrapError(err)
+				}
+			}
+			run = append(run, migration)
+		}
+		return nil
+	})
+
+	return run, err
+}
+
+// Down rolls back the last n applied migrations, newest first, and returns the ones that were rolled back
+func (m *Migrator) Down(n int) ([]*Migration, error) {
+	run := []*Migration{}
+
+	err := m.locked(func(col *mgo.Collection, held func() error) error {
+		records, err := m.applied(col)
+		if err != nil {
+			return err
+		}
+
+		ids := []string{}
+		for id := range records {
+			ids = append(ids, id)
+		}
+		sort.Sort(sort.Reverse(sort.StringSlice(ids)))
+
+		for _, id := range ids {
+			if len(run) >= n {
+				break
+			}
+
+			if err := held(); err != nil {
+				return err
+			}
+
+			migration := m.find(id)
+			if migration == nil {
+				return fmt.Errorf("Migration %s was applied but isn't registered", id)
+			}
+			if migration.Down == nil {
+				return fmt.Errorf("Migration %s can't be rolled back", id)
+			}
+
+			if !m.DryRun {
+				if err := migration.Down(m.Connection.Collection(migration.Collection)); err != nil {
+					return fmt.Errorf("Rolling back migration %s failed: %s", id, err)
+				}
+				if err := col.RemoveId(id); err != nil {
+					return wrapError(err)
+				}
+			}
+			run = append(run, migration)
+		}
+		return nil
+	})
+
+	return run, err
+}
+
+func (m *Migrator) collection(sess *mgo.Session) *mgo.Collection {
+	return sess.DB(m.Connection.DialInfo.Database).C(MigrationsCollection)
+}
+
+// applied loads the records of the applied migrations by ID
+func (m *Migrator) applied(col *mgo.Collection) (map[string]*migrationRecord, error) {
+	records := map[string]*migrationRecord{}
+	iter := col.Find(bson.M{"_id": bson.M{"$ne": migrationLockID}}).Iter()
+
+	record := &migrationRecord{}
+	for iter.Next(record) {
+		records[record.ID] = record
+		record = &migrationRecord{}
+	}
+	return records, wrapError(iter.Close())
+}
+
+// MigrationLockLostError is returned when another instance took over the migration lock while
+// migrations were running, because it couldn't be refreshed for LockTimeout. No more migrations
+// are started once the lock is lost, but the one that was running may have overlapped with the other instance
+type MigrationLockLostError struct {
+	Owner string
+}
+
+func (e *MigrationLockLostError) Error() string {
+	return "Lost the migration lock to " + e.Owner
+}
+
+// Is ...
+func (e *MigrationLockLostError) Is(target error) bool {
+	return target == ErrMigrationLocked
+}
+
+// locked runs fn while holding the migration lock, refreshing it every LockTimeout/3 so that it isn't
+// taken over while a long migration runs. fn should call held before each migration, which returns
+// a *MigrationLockLostError if the lock was lost. Dry runs don't need the lock
+func (m *Migrator) locked(fn func(col *mgo.Collection, held func() error) error) (err error) {
+	sess := m.Connection.Session.Clone()
+	defer sess.Close()
+	col := m.collection(sess)
+
+	if m.DryRun {
+		return fn(col, func() error { return nil })
+	}
+
+	now := time.Now()
+	err = col.Insert(&migrationLock{ID: migrationLockID, Owner: m.owner, LockedAt: now})
+
+	if mgo.IsDup(err) {
+		// Take over the lock if it has been abandoned
+		err = col.Update(bson.M{
+			"_id":      migrationLockID,
+			"lockedAt": bson.M{"$lt": now.Add(-m.LockTimeout)},
+		}, &migrationLock{ID: migrationLockID, Owner: m.owner, LockedAt: now})
+
+		if err == mgo.ErrNotFound {
+			return ErrMigrationLocked
+		}
+	}
+	if err != nil {
+		return wrapError(err)
+	}
+
+	heartbeat := m.refreshLock()
+	defer func() {
+		lost := heartbeat.stop()
+		if rerr := col.Remove(bson.M{"_id": migrationLockID, "owner": m.owner}); rerr != nil && rerr != mgo.ErrNotFound && err == nil {
+			err = wrapError(rerr)
+		}
+		if lost != nil && err == nil {
+			err = lost
+		}
+	}()
+
+	return fn(col, heartbeat.held)
+}
+
+// lockHeartbeat refreshes the migration lock in the background
+type lockHeartbeat struct {
+	mu   sync.Mutex
+	lost error
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// refreshLock starts refreshing the lock's lockedAt, as long as it is still owned by this migrator
+func (m *Migrator) refreshLock() *lockHeartbeat {
+	h := &lockHeartbeat{done: make(chan struct{})}
+
+	interval := m.LockTimeout / 3
+	if interval <= 0 {
+		return h
+	}
+
+	sess := m.Connection.Session.Copy()
+	col := m.collection(sess)
+	ticker := time.NewTicker(interval)
+
+	h.wg.Add(1)
+	go func() {
+		defer h.wg.Done()
+		defer sess.Close()
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-h.done:
+				return
+			case <-ticker.C:
+			}
+
+			err := col.Update(bson.M{"_id": migrationLockID, "owner": m.owner}, bson.M{"$set": bson.M{"lockedAt": time.Now()}})
+			if err == mgo.ErrNotFound {
+				lock := &migrationLock{}
+				col.FindId(migrationLockID).One(lock)
+
+				h.mu.Lock()
+				h.lost = &MigrationLockLostError{Owner: lock.Owner}
+				h.mu.Unlock()
+				return
+			}
+			// Other errors are retried on the next tick. If they last past LockTimeout the lock is
+			// taken over, which the next successful round trip finds out
+		}
+	}()
+
+	return h
+}
+
+// held returns a *MigrationLockLostError if the lock was lost
+func (h *lockHeartbeat) held() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.lost
+}
+
+// stop stops refreshing the lock, and returns a *MigrationLockLostError if it was lost
+func (h *lockHeartbeat) stop() error {
+	close(h.done)
+	h.wg.Wait()
+	return h.held()
+}
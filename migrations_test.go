@@ -0,0 +1,148 @@
+package bongo
+
+import (
+	"errors"
+	. "github.com/smartystreets/goconvey/convey"
+	"gopkg.in/mgo.v2/bson"
+	"testing"
+	"time"
+)
+
+func TestMigrations(t *testing.T) {
+	conn := getConnection()
+	defer conn.Session.Close()
+
+	Convey("Migrations", t, func() {
+		conn.Collection(MigrationsCollection).Collection().DropCollection()
+		conn.Collection("migrated").Collection().DropCollection()
+
+		migrator := NewMigrator(conn)
+		calls := []string{}
+
+		err := migrator.Register(&Migration{
+			ID:         "2_second",
+			Collection: "migrated",
+			Up: func(c *Collection) error {
+				calls = append(calls, "up 2")
+				return c.Collection().Update(bson.M{"_id": "doc"}, bson.M{"$set": bson.M{"second": true}})
+			},
+			Down: func(c *Collection) error {
+				calls = append(calls, "down 2")
+				return nil
+			},
+		}, &Migration{
+			ID:         "1_first",
+			Collection: "migrated",
+			Up: func(c *Collection) error {
+				calls = append(calls, "up 1")
+				return c.Collection().Insert(bson.M{"_id": "doc"})
+			},
+		})
+		So(err, ShouldEqual, nil)
+
+		Convey("should reject duplicate IDs", func() {
+			err := migrator.Register(&Migration{ID: "1_first", Up: func(c *Collection) error { return nil }})
+			So(err, ShouldNotEqual, nil)
+		})
+
+		Convey("should run pending migrations in order, once", func() {
+			applied, err := migrator.Up()
+			So(err, ShouldEqual, nil)
+			So(len(applied), ShouldEqual, 2)
+			So(calls, ShouldResemble, []string{"up 1", "up 2"})
+
+			applied, err = migrator.Up()
+			So(err, ShouldEqual, nil)
+			So(len(applied), ShouldEqual, 0)
+
+			statuses, err := migrator.Status()
+			So(err, ShouldEqual, nil)
+			So(len(statuses), ShouldEqual, 2)
+			So(statuses[0].ID, ShouldEqual, "1_first")
+			So(statuses[0].Applied, ShouldEqual, true)
+
+			count, _ := conn.Collection("migrated").Collection().Find(bson.M{"second": true}).Count()
+			So(count, ShouldEqual, 1)
+		})
+
+		Convey("should roll back, newest first", func() {
+			migrator.Up()
+
+			rolledBack, err := migrator.Down(1)
+			So(err, ShouldEqual, nil)
+			So(len(rolledBack), ShouldEqual, 1)
+			So(rolledBack[0].ID, ShouldEqual, "2_second")
+
+			_, err = migrator.Down(1)
+			So(err, ShouldNotEqual, nil)
+
+			statuses, _ := migrator.Status()
+			So(statuses[0].Applied, ShouldEqual, true)
+			So(statuses[1].Applied, ShouldEqual, false)
+		})
+
+		Convey("should not run anything for a dry run", func() {
+			migrator.DryRun = true
+			applied, err := migrator.Up()
+			So(err, ShouldEqual, nil)
+			So(len(applied), ShouldEqual, 2)
+			So(len(calls), ShouldEqual, 0)
+		})
+
+		Convey("should stop at a failing migration", func() {
+			migrator.Register(&Migration{
+				ID:         "3_broken",
+				Collection: "migrated",
+				Up: func(c *Collection) error {
+					return errors.New("broken")
+				},
+			})
+
+			applied, err := migrator.Up()
+			So(err, ShouldNotEqual, nil)
+			So(len(applied), ShouldEqual, 2)
+
+			statuses, _ := migrator.Status()
+			So(statuses[2].Applied, ShouldEqual, false)
+		})
+
+		Convey("should respect the lock", func() {
+			conn.Collection(MigrationsCollection).Collection().Insert(&migrationLock{
+				ID:       migrationLockID,
+				Owner:    "other",
+				LockedAt: time.Now(),
+			})
+
+			_, err := migrator.Up()
+			So(err, ShouldEqual, ErrMigrationLocked)
+
+			migrator.LockTimeout = 0
+			_, err = migrator.Up()
+			So(err, ShouldEqual, nil)
+		})
+
+		Convey("should keep the lock while a long migration runs", func() {
+			migrator.LockTimeout = 300 * time.Millisecond
+
+			other := NewMigrator(conn)
+			other.owner = "other"
+			other.LockTimeout = migrator.LockTimeout
+
+			var otherErr error
+			migrator.Register(&Migration{
+				ID:         "3_slow",
+				Collection: "migrated",
+				Up: func(c *Collection) error {
+					time.Sleep(2 * migrator.LockTimeout)
+					_, otherErr = other.Up()
+					return nil
+				},
+			})
+
+			applied, err := migrator.Up()
+			So(err, ShouldEqual, nil)
+			So(len(applied), ShouldEqual, 3)
+			So(errors.Is(otherErr, ErrMigrationLocked), ShouldEqual, true)
+		})
+	})
+}
@@ -0,0 +1,121 @@
+package bongo
+
+import (
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"gopkg.in/mgo.v2"
+)
+
+// CascadeEvent describes a cascade config being applied
+type CascadeEvent struct {
+	// CascadeOpSave or CascadeOpDelete
+	Op int
+	// The collection and _id of the document that cascades
+	Collection string
+	ID         interface{}
+	// The config being applied. It is nil for errors that happen before any config is applied
+	// (e.g. a panic in GetCascade), which only get a finish event
+	Config *CascadeConfig
+	// The collection cascaded to
+	Target  string
+	Started time.Time
+	// Set when the config has been applied. For OnDeleteCascade configs ChangeInfo is nil
+	ChangeInfo *mgo.ChangeInfo
+	Duration   time.Duration
+	Err        error
+}
+
+// CascadeObserver is notified of every cascade config that is applied. Cascades often run in a
+// goroutine, so this is the way to log or alert on their failures. Set it on the Connection.
+// The methods may be called concurrently
+type CascadeObserver interface {
+	CascadeStarted(event *CascadeEvent)
+	CascadeFinished(event *CascadeEvent)
+}
+
+// CascadePanicError is a panic recovered while cascading
+type CascadePanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (c *CascadePanicError) Error() string {
+	return fmt.Sprintf("Cascade panicked: %v", c.Value)
+}
+
+// Unwrap returns the value of the panic if it is an error
+func (c *CascadePanicError) Unwrap() error {
+	if err, ok := c.Value.(error); ok {
+		return err
+	}
+	return nil
+}
+
+func cascadeObserver(collection *Collection) CascadeObserver {
+	if collection == nil || collection.Connection == nil {
+		return nil
+	}
+	return collection.Connection.CascadeObserver
+}
+
+// observeCascade applies a config with fn, reporting it to the connection's CascadeObserver.
+// A panic in fn is returned as a *CascadePanicError
+func observeCascade(collection *Collection, op int, id interface{}, conf *CascadeConfig, fn func() (*mgo.ChangeInfo, error)) (err error) {
+	observer := cascadeObserver(collection)
+
+	event := &CascadeEvent{
+		Op:         op,
+		Collection: collection.Name,
+		ID:         id,
+		Config:     conf,
+		Started:    time.Now(),
+	}
+	if conf.Collection != nil {
+		event.Target = conf.Collection.Name
+	}
+
+	if observer != nil {
+		observer.CascadeStarted(event)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = &CascadePanicError{Value: r, Stack: debug.Stack()}
+		}
+
+		event.Duration = time.Since(event.Started)
+		event.Err = err
+		if observer != nil {
+			observer.CascadeFinished(event)
+		}
+	}()
+
+	event.ChangeInfo, err = fn()
+	return err
+}
+
+// recoverCascade is deferred by CascadeSave, CascadeDelete and the delete restriction check. It
+// turns a panic into an error, and reports errors that didn't come from a config (those have been
+// reported by observeCascade)
+func recoverCascade(collection *Collection, op int, doc interface{}, err *error) {
+	if r := recover(); r != nil {
+		*err = &CascadePanicError{Value: r, Stack: debug.Stack()}
+	}
+
+	if *err == nil {
+		return
+	}
+	if _, ok := (*err).(*CascadeError); ok {
+		return
+	}
+
+	if observer := cascadeObserver(collection); observer != nil {
+		event := &CascadeEvent{Op: op, Collection: collection.Name, Started: time.Now(), Err: *err}
+		if document, ok := doc.(Document); ok {
+			event.ID = document.GetID()
+		}
+		observer.CascadeFinished(event)
+	}
+}
@@ -0,0 +1,105 @@
+package bongo
+
+import (
+	"errors"
+	. "github.com/smartystreets/goconvey/convey"
+	"gopkg.in/mgo.v2/bson"
+	"sync"
+	"testing"
+)
+
+type recordingObserver struct {
+	mutex    sync.Mutex
+	started  []*CascadeEvent
+	finished []*CascadeEvent
+}
+
+func (r *recordingObserver) CascadeStarted(event *CascadeEvent) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.started = append(r.started, event)
+}
+
+func (r *recordingObserver) CascadeFinished(event *CascadeEvent) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.finished = append(r.finished, event)
+}
+
+type panickingCascade struct {
+	DocumentBase `bson:",inline"`
+}
+
+func (p *panickingCascade) GetCascade(collection *Collection) []*CascadeConfig {
+	panic("no cascade for you")
+}
+
+// Has no ID field, so the reference query can't be built
+type idlessCascade struct {
+	Name string
+}
+
+func (i *idlessCascade) GetCascade(collection *Collection) []*CascadeConfig {
+	return []*CascadeConfig{
+		&CascadeConfig{
+			Collection: collection,
+			RelType:    RelOne,
+			Query:      bson.M{"name": i.Name},
+		},
+	}
+}
+
+func TestCascadeObserver(t *testing.T) {
+	Convey("CascadeObserver", t, func() {
+		observer := &recordingObserver{}
+		conn := &Connection{CascadeObserver: observer}
+		collection := conn.Collection("things")
+
+		Convey("should get start and finish events with errors", func() {
+			doc := &BrokenCascade{}
+			doc.ID = bson.NewObjectId()
+
+			err := CascadeSave(collection, doc)
+			So(errors.Is(err, ErrCascade), ShouldEqual, true)
+
+			So(len(observer.started), ShouldEqual, 1)
+			So(len(observer.finished), ShouldEqual, 1)
+
+			event := observer.finished[0]
+			So(event.Op, ShouldEqual, CascadeOpSave)
+			So(event.Collection, ShouldEqual, "things")
+			So(event.ID, ShouldEqual, doc.ID)
+			So(event.Target, ShouldEqual, "parents")
+			So(event.Err.Error(), ShouldEqual, "Invalid relation type")
+			So(event.Started.IsZero(), ShouldEqual, false)
+		})
+
+		Convey("should turn panics into errors", func() {
+			err := CascadeSave(collection, &panickingCascade{})
+
+			var panicErr *CascadePanicError
+			So(errors.As(err, &panicErr), ShouldEqual, true)
+			So(panicErr.Value, ShouldEqual, "no cascade for you")
+
+			So(len(observer.finished), ShouldEqual, 1)
+			So(observer.finished[0].Config, ShouldBeNil)
+			So(observer.finished[0].Err, ShouldEqual, err)
+		})
+
+		Convey("should turn panics while checking delete restrictions into errors", func() {
+			err := checkDeleteRestrictions(collection, &panickingCascade{})
+
+			var panicErr *CascadePanicError
+			So(errors.As(err, &panicErr), ShouldEqual, true)
+			So(panicErr.Value, ShouldEqual, "no cascade for you")
+		})
+
+		Convey("should not panic if the ID of a deleted document can't be read", func() {
+			var err error
+			So(func() {
+				err = CascadeDelete(collection, &idlessCascade{Name: "x"})
+			}, ShouldNotPanic)
+			So(errors.Is(err, ErrCascade), ShouldEqual, true)
+		})
+	})
+}
@@ -0,0 +1,267 @@
+package bongo
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// Relation declares that a model cascades its data to related documents, so it doesn't need to
+// implement GetCascade. Declare it with a tag on any field of the model:
+//
+//	TeamID bson.ObjectId `bongo:"cascade=teams.players,rel=many,props=firstName|lastName"`
+//
+// or with RegisterRelation
+type Relation struct {
+	// The collection to cascade to
+	Collection string
+	// The property on the related doc to populate. Empty to cascade to the root of the related doc
+	ThroughProp string
+	// RelMany or RelOne
+	RelType int
+	// Name of the struct field holding the _id of the related doc. Defaults to the tagged field
+	Ref string
+	// The properties to cascade, as passed to MapFromCascadeProperties. When there is a ThroughProp,
+	// _id is always cascaded as well
+	Properties []string
+	// For RelMany, see CascadeConfig.SortKey and CascadeConfig.MaxLength
+	SortKey   string
+	MaxLength int
+	// The delete policy, see CascadeConfig.OnDelete. OnDeleteCascade needs an Instance, so it can't be
+	// declared with a tag
+	OnDelete int
+	Instance Document
+}
+
+var relationRegistry = struct {
+	sync.RWMutex
+	declared   map[reflect.Type][]*Relation
+	registered map[reflect.Type][]*Relation
+}{
+	declared:   make(map[reflect.Type][]*Relation),
+	registered: make(map[reflect.Type][]*Relation),
+}
+
+// RegisterRelation adds relations to the ones declared by the tags of the prototype's type.
+// Call it at startup
+func RegisterRelation(prototype interface{}, relations ...*Relation) error {
+	t := indirectType(reflect.TypeOf(prototype))
+
+	for _, rel := range relations {
+		if err := rel.check(t); err != nil {
+			return err
+		}
+	}
+
+	relationRegistry.Lock()
+	defer relationRegistry.Unlock()
+	relationRegistry.registered[t] = append(relationRegistry.registered[t], relations...)
+	return nil
+}
+
+// Relations returns the relations declared by the tags of the doc's type, followed by the registered ones
+func Relations(doc interface{}) ([]*Relation, error) {
+	t := indirectType(reflect.TypeOf(doc))
+
+	relationRegistry.RLock()
+	declared, ok := relationRegistry.declared[t]
+	registered := relationRegistry.registered[t]
+	relationRegistry.RUnlock()
+
+	if !ok {
+		var err error
+		if declared, err = parseRelations(t); err != nil {
+			return nil, err
+		}
+
+		relationRegistry.Lock()
+		relationRegistry.declared[t] = declared
+		relationRegistry.Unlock()
+	}
+
+	return append(append([]*Relation{}, declared...), registered...), nil
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// parseRelations reads the cascade tags of the top level (and inlined) fields of a struct type
+func parseRelations(t reflect.Type) ([]*Relation, error) {
+	relations := []*Relation{}
+	if t == nil || t.Kind() != reflect.Struct {
+		return relations, nil
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		if field.Anonymous && hasBsonFlag(field, "inline") {
+			inlined, err := parseRelations(indirectType(field.Type))
+			if err != nil {
+				return nil, err
+			}
+			relations = append(relations, inlined...)
+			continue
+		}
+
+		opts := parseTag(field)
+		if !hasTagOption(opts, "cascade") {
+			continue
+		}
+
+		rel := &Relation{RelType: RelOne, Ref: field.Name}
+		for _, opt := range opts {
+			switch opt.Key {
+			case "cascade":
+				split := strings.SplitN(opt.Value, ".", 2)
+				rel.Collection = split[0]
+				if len(split) > 1 {
+					rel.ThroughProp = split[1]
+				}
+			case "rel":
+				switch opt.Value {
+				case "one":
+					rel.RelType = RelOne
+				case "many":
+					rel.RelType = RelMany
+				default:
+					return nil, fmt.Errorf("%s.%s: invalid rel %q, must be one or many", t.Name(), field.Name, opt.Value)
+				}
+			case "ref":
+				rel.Ref = opt.Value
+			case "props":
+				rel.Properties = strings.Split(opt.Value, "|")
+			case "sort":
+				rel.SortKey = opt.Value
+			case "limit":
+				n, err := strconv.Atoi(opt.Value)
+				if err != nil || n < 1 {
+					return nil, fmt.Errorf("%s.%s: invalid limit %q", t.Name(), field.Name, opt.Value)
+				}
+				rel.MaxLength = n
+			case "ondelete":
+				switch opt.Value {
+				case "nullify":
+					rel.OnDelete = OnDeleteNullify
+				case "restrict":
+					rel.OnDelete = OnDeleteRestrict
+				default:
+					return nil, fmt.Errorf("%s.%s: invalid ondelete %q, must be nullify or restrict", t.Name(), field.Name, opt.Value)
+				}
+			}
+		}
+
+		if err := rel.check(t); err != nil {
+			return nil, fmt.Errorf("%s.%s: %s", t.Name(), field.Name, err)
+		}
+		relations = append(relations, rel)
+	}
+
+	return relations, nil
+}
+
+// check makes sure the relation is complete and its Ref field exists on the type
+func (r *Relation) check(t reflect.Type) error {
+	if len(r.Collection) == 0 {
+		return fmt.Errorf("Relation has no collection")
+	}
+	if r.RelType == RelMany && len(r.ThroughProp) == 0 {
+		return fmt.Errorf("Relation to %s: rel=many needs a through property", r.Collection)
+	}
+	if len(r.Properties) == 0 {
+		return fmt.Errorf("Relation to %s has no properties", r.Collection)
+	}
+	if r.OnDelete == OnDeleteCascade && r.Instance == nil {
+		return fmt.Errorf("Relation to %s: OnDeleteCascade needs an Instance", r.Collection)
+	}
+	if _, ok := t.FieldByName(r.Ref); !ok {
+		return fmt.Errorf("Relation to %s: %s has no field %q", r.Collection, t.Name(), r.Ref)
+	}
+	return nil
+}
+
+// Config builds the CascadeConfig of the relation for the document. If the document is Trackable
+// and its Ref field has changed, OldQuery targets the previously related document
+func (r *Relation) Config(collection *Collection, doc Document) *CascadeConfig {
+	v := reflect.Indirect(reflect.ValueOf(doc))
+	ref := v.FieldByName(r.Ref).Interface()
+
+	props := r.Properties
+	data := MapFromCascadeProperties(props, doc)
+
+	if len(r.ThroughProp) > 0 {
+		if _, ok := data["_id"]; !ok {
+			props = append([]string{"_id"}, props...)
+			data["_id"] = doc.GetID()
+		}
+	}
+
+	conf := &CascadeConfig{
+		Collection:     collection.Connection.Collection(r.Collection),
+		RelType:        r.RelType,
+		ThroughProp:    r.ThroughProp,
+		Query:          bson.M{"_id": ref},
+		Properties:     props,
+		Data:           data,
+		ReferenceQuery: []*ReferenceField{&ReferenceField{"_id", doc.GetID()}},
+		SortKey:        r.SortKey,
+		MaxLength:      r.MaxLength,
+		OnDelete:       r.OnDelete,
+		Instance:       r.Instance,
+	}
+
+	// Without a tracker the ref counts as modified, but there's no previous value to target
+	if trackable, ok := doc.(Trackable); ok {
+		tracker := trackable.GetDiffTracker()
+
+		if tracker != nil && tracker.Modified(r.Ref) {
+			orig, err := tracker.GetOriginalValue(r.Ref)
+			if err == nil && orig != nil && !reflect.ValueOf(orig).IsZero() {
+				conf.OldQuery = bson.M{"_id": orig}
+			}
+		}
+	}
+
+	return conf
+}
+
+// cascadeConfigs returns the configs of the document's relations, or the ones returned by
+// GetCascade if it has no declared relations
+func cascadeConfigs(collection *Collection, doc interface{}) ([]*CascadeConfig, error) {
+	relations, err := Relations(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(relations) > 0 {
+		if document, ok := doc.(Document); ok {
+			configs := make([]*CascadeConfig, len(relations))
+			for i, rel := range relations {
+				configs[i] = rel.Config(collection, document)
+			}
+			return configs, nil
+		}
+	}
+
+	if conv, ok := doc.(CascadingDocument); ok {
+		return conv.GetCascade(collection), nil
+	}
+	return nil, nil
+}
+
+// hasCascades tells if documents of the prototype's type cascade, by relations or GetCascade
+func hasCascades(prototype interface{}) bool {
+	if _, ok := prototype.(CascadingDocument); ok {
+		return true
+	}
+	relations, _ := Relations(prototype)
+	return len(relations) > 0
+}
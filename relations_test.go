@@ -0,0 +1,141 @@
+package bongo
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"gopkg.in/mgo.v2/bson"
+	"testing"
+)
+
+type relationPlayer struct {
+	DocumentBase `bson:",inline"`
+	FirstName    string        `bson:"firstName"`
+	LastName     string        `bson:"lastName"`
+	TeamID       bson.ObjectId `bson:"teamId,omitempty" bongo:"required,cascade=relationTeams.players,rel=many,props=firstName|lastName"`
+	CaptainOf    bson.ObjectId `bson:"captainOf,omitempty" bongo:"cascade=relationTeams.captain,props=firstName"`
+	diffTracker  *DiffTracker
+}
+
+func (p *relationPlayer) GetDiffTracker() *DiffTracker {
+	if p.diffTracker == nil {
+		p.diffTracker = NewDiffTracker(p)
+	}
+	return p.diffTracker
+}
+
+type relationTeamRef struct {
+	ID        bson.ObjectId `bson:"_id"`
+	FirstName string        `bson:"firstName"`
+	LastName  string        `bson:"lastName"`
+}
+
+type relationTeam struct {
+	DocumentBase `bson:",inline"`
+	Players      []relationTeamRef `bson:"players"`
+	Captain      *relationTeamRef  `bson:"captain"`
+}
+
+// Its tracker is never initialized
+type untrackedRelationPlayer struct {
+	DocumentBase `bson:",inline"`
+	FirstName    string        `bson:"firstName"`
+	TeamID       bson.ObjectId `bson:"teamId" bongo:"cascade=relationTeams.players,rel=many,props=firstName"`
+	diffTracker  *DiffTracker
+}
+
+func (p *untrackedRelationPlayer) GetDiffTracker() *DiffTracker {
+	return p.diffTracker
+}
+
+type badRelation struct {
+	DocumentBase `bson:",inline"`
+	TeamID       bson.ObjectId `bongo:"cascade=teams.players,rel=lots,props=name"`
+}
+
+func TestRelations(t *testing.T) {
+	Convey("Relations", t, func() {
+		Convey("should be read from tags", func() {
+			relations, err := Relations(&relationPlayer{})
+			So(err, ShouldEqual, nil)
+			So(len(relations), ShouldEqual, 2)
+			So(relations[0], ShouldResemble, &Relation{
+				Collection:  "relationTeams",
+				ThroughProp: "players",
+				RelType:     RelMany,
+				Ref:         "TeamID",
+				Properties:  []string{"firstName", "lastName"},
+			})
+			So(relations[1].RelType, ShouldEqual, RelOne)
+			So(relations[1].Ref, ShouldEqual, "CaptainOf")
+		})
+
+		Convey("should reject invalid tags", func() {
+			_, err := Relations(&badRelation{})
+			So(err, ShouldNotEqual, nil)
+
+			err = RegisterRelation(&badRelation{}, &Relation{Collection: "teams", Ref: "Missing", Properties: []string{"name"}})
+			So(err, ShouldNotEqual, nil)
+		})
+
+		Convey("should build cascade configs", func() {
+			collection := (&Connection{}).Collection("relationPlayers")
+			player := &relationPlayer{FirstName: "Jo", TeamID: bson.NewObjectId()}
+			player.ID = bson.NewObjectId()
+
+			relations, _ := Relations(player)
+			conf := relations[0].Config(collection, player)
+
+			So(conf.Collection.Name, ShouldEqual, "relationTeams")
+			So(conf.Query, ShouldResemble, bson.M{"_id": player.TeamID})
+			So(conf.Properties, ShouldResemble, []string{"_id", "firstName", "lastName"})
+			So(conf.ReferenceQuery[0].Value, ShouldEqual, player.ID)
+			So(conf.OldQuery, ShouldBeNil)
+
+			Convey("with an OldQuery if the ref changed", func() {
+				oldTeam := player.TeamID
+				player.GetDiffTracker().Reset()
+				player.TeamID = bson.NewObjectId()
+
+				conf := relations[0].Config(collection, player)
+				So(conf.OldQuery, ShouldResemble, bson.M{"_id": oldTeam})
+			})
+
+			Convey("without an OldQuery if the document has no tracker", func() {
+				untracked := &untrackedRelationPlayer{TeamID: bson.NewObjectId()}
+				untracked.ID = bson.NewObjectId()
+
+				relations, err := Relations(untracked)
+				So(err, ShouldEqual, nil)
+
+				var conf *CascadeConfig
+				So(func() { conf = relations[0].Config(collection, untracked) }, ShouldNotPanic)
+				So(conf.OldQuery, ShouldBeNil)
+			})
+		})
+	})
+
+	Convey("Declared relations cascade on save", t, func() {
+		conn := getConnection()
+		defer conn.Session.Close()
+		conn.Session.DB("bongotest").DropDatabase()
+
+		teams := conn.Collection("relationTeams")
+		players := conn.Collection("relationPlayers")
+		players.SyncCascade = true
+
+		team := &relationTeam{}
+		So(teams.Save(team), ShouldEqual, nil)
+		player := &relationPlayer{FirstName: "Jo", LastName: "Bongo", TeamID: team.ID, CaptainOf: team.ID}
+		So(players.Save(player), ShouldEqual, nil)
+
+		saved := &relationTeam{}
+		So(teams.FindByID(team.ID, saved), ShouldEqual, nil)
+		So(saved.Players, ShouldResemble, []relationTeamRef{{player.ID, "Jo", "Bongo"}})
+		So(saved.Captain.FirstName, ShouldEqual, "Jo")
+
+		So(players.Delete(player), ShouldEqual, nil)
+		saved = &relationTeam{}
+		So(teams.FindByID(team.ID, saved), ShouldEqual, nil)
+		So(len(saved.Players), ShouldEqual, 0)
+		So(saved.Captain, ShouldEqual, nil)
+	})
+}
@@ -0,0 +1,150 @@
+package bongo
+
+import (
+	"context"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// DocumentPtr is a pointer to a document struct, for Repo
+type DocumentPtr[T any] interface {
+	*T
+	Document
+}
+
+// Repo is a typed wrapper around a Collection, for documents of type T. Create one with NewRepo,
+// e.g. NewRepo[Person](connection.Collection("people")). It runs the same hooks, cascades and
+// soft delete scoping as the Collection methods it wraps
+type Repo[T any, PT DocumentPtr[T]] struct {
+	Collection *Collection
+}
+
+// NewRepo ...
+func NewRepo[T any, PT DocumentPtr[T]](collection *Collection) *Repo[T, PT] {
+	return &Repo[T, PT]{Collection: collection}
+}
+
+// Get finds a document by id, see Collection.FindByID
+func (r *Repo[T, PT]) Get(id bson.ObjectId, opts ...FindOption) (*T, error) {
+	return r.GetCtx(context.Background(), id, opts...)
+}
+
+// GetCtx is Get with a context
+func (r *Repo[T, PT]) GetCtx(ctx context.Context, id bson.ObjectId, opts ...FindOption) (*T, error) {
+	doc := new(T)
+	if err := r.Collection.FindByIDCtx(ctx, id, doc, opts...); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// FindOne finds the first document matching the filter, see Collection.FindOne
+func (r *Repo[T, PT]) FindOne(filter interface{}, opts ...FindOption) (*T, error) {
+	return r.FindOneCtx(context.Background(), filter, opts...)
+}
+
+// FindOneCtx is FindOne with a context
+func (r *Repo[T, PT]) FindOneCtx(ctx context.Context, filter interface{}, opts ...FindOption) (*T, error) {
+	doc := new(T)
+	if err := r.Collection.FindOneCtx(ctx, filter, doc, opts...); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// Find returns an iterator over the documents matching the filter
+func (r *Repo[T, PT]) Find(filter interface{}, opts ...FindOption) *Iterator[T] {
+	return r.FindCtx(context.Background(), filter, opts...)
+}
+
+// FindCtx is Find with a context, see Collection.FindCtx
+func (r *Repo[T, PT]) FindCtx(ctx context.Context, filter interface{}, opts ...FindOption) *Iterator[T] {
+	return &Iterator[T]{ResultSet: r.Collection.FindCtx(ctx, filter, opts...)}
+}
+
+// Save ...
+func (r *Repo[T, PT]) Save(doc *T) error {
+	return r.Collection.Save(PT(doc))
+}
+
+// SaveCtx ...
+func (r *Repo[T, PT]) SaveCtx(ctx context.Context, doc *T) error {
+	return r.Collection.SaveCtx(ctx, PT(doc))
+}
+
+// Delete ...
+func (r *Repo[T, PT]) Delete(doc *T) error {
+	return r.Collection.Delete(PT(doc))
+}
+
+// DeleteCtx ...
+func (r *Repo[T, PT]) DeleteCtx(ctx context.Context, doc *T) error {
+	return r.Collection.DeleteCtx(ctx, PT(doc))
+}
+
+// Count counts the documents matching the filter
+func (r *Repo[T, PT]) Count(filter interface{}, opts ...FindOption) (int, error) {
+	return r.CountCtx(context.Background(), filter, opts...)
+}
+
+// CountCtx is Count with a context
+func (r *Repo[T, PT]) CountCtx(ctx context.Context, filter interface{}, opts ...FindOption) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, wrapError(err)
+	}
+
+	n, err := r.Collection.FindCtx(ctx, filter, opts...).Query.Count()
+	return n, wrapError(err)
+}
+
+// Exists tells if any document matches the filter
+func (r *Repo[T, PT]) Exists(filter interface{}, opts ...FindOption) (bool, error) {
+	return r.ExistsCtx(context.Background(), filter, opts...)
+}
+
+// ExistsCtx is Exists with a context
+func (r *Repo[T, PT]) ExistsCtx(ctx context.Context, filter interface{}, opts ...FindOption) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, wrapError(err)
+	}
+
+	n, err := r.Collection.FindCtx(ctx, filter, opts...).Query.Limit(1).Count()
+	return n > 0, wrapError(err)
+}
+
+// Iterator is a typed ResultSet. The ResultSet can still be used to sort or paginate before iterating
+type Iterator[T any] struct {
+	ResultSet *ResultSet
+	doc       *T
+}
+
+// Next loads the next document, which is then returned by Doc. Every document is a new *T
+func (it *Iterator[T]) Next() bool {
+	doc := new(T)
+	if !it.ResultSet.Next(doc) {
+		it.doc = nil
+		return false
+	}
+	it.doc = doc
+	return true
+}
+
+// Doc returns the document loaded by the last call to Next
+func (it *Iterator[T]) Doc() *T {
+	return it.doc
+}
+
+// Err returns the error that stopped the iteration, if any
+func (it *Iterator[T]) Err() error {
+	return it.ResultSet.Error
+}
+
+// Close ...
+func (it *Iterator[T]) Close() error {
+	return it.ResultSet.Free()
+}
+
+// All loads the remaining documents, see ResultSet.All
+func (it *Iterator[T]) All() ([]*T, error) {
+	return Collect[*T](it.ResultSet)
+}
@@ -0,0 +1,77 @@
+package bongo
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestRepo(t *testing.T) {
+	conn := getConnection()
+	defer conn.Session.Close()
+
+	repo := NewRepo[hookedDocument](conn.Collection("tests"))
+
+	Convey("Repo", t, func() {
+		doc := &hookedDocument{}
+		So(repo.Save(doc), ShouldEqual, nil)
+		So(doc.RanBeforeSave, ShouldEqual, true)
+		So(repo.Save(&hookedDocument{}), ShouldEqual, nil)
+
+		Convey("should get documents by id and query", func() {
+			found, err := repo.Get(doc.ID)
+			So(err, ShouldEqual, nil)
+			So(found.ID, ShouldEqual, doc.ID)
+			So(found.RanAfterFind, ShouldEqual, true)
+
+			found, err = repo.FindOne(bson.M{"_id": doc.ID})
+			So(err, ShouldEqual, nil)
+			So(found.ID, ShouldEqual, doc.ID)
+
+			found, err = repo.Get(bson.NewObjectId())
+			So(found, ShouldBeNil)
+			So(errors.Is(err, ErrNotFound), ShouldEqual, true)
+		})
+
+		Convey("should iterate over typed documents", func() {
+			it := repo.Find(nil)
+			defer it.Close()
+
+			ids := []bson.ObjectId{}
+			for it.Next() {
+				So(it.Doc().RanAfterFind, ShouldEqual, true)
+				ids = append(ids, it.Doc().ID)
+			}
+			So(it.Err(), ShouldEqual, nil)
+			So(len(ids), ShouldEqual, 2)
+			So(ids[0], ShouldNotEqual, ids[1])
+
+			docs, err := repo.Find(nil).All()
+			So(err, ShouldEqual, nil)
+			So(len(docs), ShouldEqual, 2)
+		})
+
+		Convey("should count, check existence and delete", func() {
+			n, err := repo.Count(nil)
+			So(err, ShouldEqual, nil)
+			So(n, ShouldEqual, 2)
+
+			exists, err := repo.Exists(bson.M{"_id": doc.ID})
+			So(err, ShouldEqual, nil)
+			So(exists, ShouldEqual, true)
+
+			So(repo.Delete(doc), ShouldEqual, nil)
+			So(doc.RanAfterDelete, ShouldEqual, true)
+
+			exists, err = repo.Exists(bson.M{"_id": doc.ID})
+			So(err, ShouldEqual, nil)
+			So(exists, ShouldEqual, false)
+		})
+
+		Reset(func() {
+			conn.Session.DB("bongotest").DropDatabase()
+		})
+	})
+}
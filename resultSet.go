@@ -1,8 +1,13 @@
 package bongo
 
 import (
-	"gopkg.in/mgo.v2"
+	"context"
 	"math"
+	"strings"
+	"time"
+
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
 )
 
 // ResultSet ...
@@ -13,50 +18,154 @@ type ResultSet struct {
 	Collection *Collection
 	Error      error
 	Params     interface{}
+	ctx        context.Context
+
+	// Set through Sort, Select and Hint, so PaginateAfter can build its page query
+	sort     []string
+	selector interface{}
+	hint     []string
+	// Loaded by PaginateAfter, and read by Next instead of the query
+	page  []bson.Raw
+	paged bool
 }
 
 // PaginationInfo ...
 type PaginationInfo struct {
-	Current       int `json:"current"`
-	TotalPages    int `json:"totalPages"`
-	PerPage       int `json:"perPage"`
-	TotalRecords  int `json:"totalRecords"`
-	RecordsOnPage int `json:"recordsOnPage"`
+	Current    int `json:"current"`
+	TotalPages int `json:"totalPages"`
+	PerPage    int `json:"perPage"`
+	// -1 (as is TotalPages) when the count was skipped with SkipCount
+	TotalRecords  int  `json:"totalRecords"`
+	RecordsOnPage int  `json:"recordsOnPage"`
+	HasNextPage   bool `json:"hasNextPage"`
+	// The total is only a lower bound (e.g. "1000+"), see CountLimit
+	TotalCapped bool `json:"totalCapped,omitempty"`
+	// The total came from the collection's metadata, see EstimatedCount
+	TotalEstimated bool `json:"totalEstimated,omitempty"`
+}
+
+type paginateOptions struct {
+	estimated bool
+	limit     int
+	skipCount bool
+}
+
+// PaginateOption changes how Paginate counts the result set
+type PaginateOption func(*paginateOptions)
+
+// EstimatedCount uses the collection's metadata for the total instead of counting. It's instant,
+// but may be off after unclean shutdowns or on sharded clusters. Paginate returns
+// ErrEstimateUnavailable if the result set has a query, which includes the deletedAt filter of
+// soft delete collections (unless WithDeleted was passed)
+func EstimatedCount() PaginateOption {
+	return func(o *paginateOptions) {
+		o.estimated = true
+	}
+}
+
+// CountLimit stops counting after max documents, and sets TotalCapped if there are more
+func CountLimit(max int) PaginateOption {
+	return func(o *paginateOptions) {
+		o.limit = max
+	}
+}
+
+// SkipCount doesn't count the result set at all. Only the current page and whether there's another
+// one after it are looked at
+func SkipCount() PaginateOption {
+	return func(o *paginateOptions) {
+		o.skipCount = true
+	}
+}
+
+// Sort sets the order of the results, with fields in the format of mgo's Query.Sort ("field" or "-field").
+// PaginateAfter uses it when it isn't given sort fields
+func (r *ResultSet) Sort(fields ...string) *ResultSet {
+	r.sort = fields
+	r.Query.Sort(fields...)
+	return r
+}
+
+// Select sets the fields to load, as with mgo's Query.Select
+func (r *ResultSet) Select(selector interface{}) *ResultSet {
+	r.selector = selector
+	r.Query.Select(selector)
+	return r
+}
+
+// Hint forces the index to use, as with mgo's Query.Hint. Paginate counts with it too
+func (r *ResultSet) Hint(indexKey ...string) *ResultSet {
+	r.hint = indexKey
+	r.Query.Hint(indexKey...)
+	return r
 }
 
 // Next ...
 func (r *ResultSet) Next(doc interface{}) bool {
+	ctx := r.context()
 
-	// Check if the iter has been instantiated yet
-	if !r.loadedIter {
-		r.Iter = r.Query.Iter()
-		r.loadedIter = true
+	if err := ctx.Err(); err != nil {
+		r.Error = wrapError(err)
+		return false
 	}
 
-	gotResult := r.Iter.Next(doc)
+	var gotResult bool
+
+	if r.paged {
+		gotResult = r.nextOnPage(doc)
+	} else {
+		// Check if the iter has been instantiated yet
+		if !r.loadedIter {
+			r.Iter = r.Query.Iter()
+			r.loadedIter = true
+		}
+
+		gotResult = r.Iter.Next(doc)
+		if err := r.Iter.Err(); !gotResult && err != nil {
+			r.Error = wrapError(err)
+		}
+	}
 
 	if gotResult {
 
-		if hook, ok := doc.(AfterFindHook); ok {
-			err := hook.AfterFind(r.Collection)
-			if err != nil {
-				r.Error = err
-				return false
-			}
+		err := runAfterFind(ctx, r.Collection, doc)
+		if err != nil {
+			r.Error = err
+			return false
 		}
 
 		if newt, ok := doc.(NewTracker); ok {
 			newt.SetIsNew(false)
 		}
+		resetTracker(doc)
 		return true
 	}
 
-	err := r.Iter.Err()
-	if err != nil {
+	return false
+}
+
+// nextOnPage decodes the next document of the page loaded by PaginateAfter
+func (r *ResultSet) nextOnPage(doc interface{}) bool {
+	if len(r.page) == 0 {
+		return false
+	}
+
+	raw := r.page[0]
+	r.page = r.page[1:]
+
+	if err := raw.Unmarshal(doc); err != nil {
 		r.Error = err
+		return false
 	}
+	return true
+}
 
-	return false
+// context returns the context the result set was created with by FindCtx
+func (r *ResultSet) context() context.Context {
+	if r.ctx == nil {
+		return context.Background()
+	}
+	return r.ctx
 }
 
 // Free ...
@@ -70,31 +179,68 @@ func (r *ResultSet) Free() error {
 	return nil
 }
 
-// Paginate Set skip + limit on the current query and generates a PaginationInfo struct with info for your front end
-func (r *ResultSet) Paginate(perPage, page int) (*PaginationInfo, error) {
+// Paginate Set skip + limit on the current query and generates a PaginationInfo struct with info for your front end.
+// By default it counts every matching document, see PaginateOption for cheaper alternatives
+func (r *ResultSet) Paginate(perPage, page int, opts ...PaginateOption) (*PaginationInfo, error) {
+	options := &paginateOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
 
 	info := new(PaginationInfo)
 
+	ctx := r.context()
+	if err := ctx.Err(); err != nil {
+		return info, wrapError(err)
+	}
+
 	// Get count on a different session to avoid blocking
 	sess := r.Collection.Connection.Session.Copy()
+	defer sess.Close()
+
+	db := sess.DB(r.Collection.Connection.DialInfo.Database)
 
-	count, err := sess.DB(r.Collection.Connection.DialInfo.Database).C(r.Collection.Name).Find(r.Params).Count()
-	sess.Close()
+	count := -1
+	var err error
+
+	switch {
+	case options.skipCount:
+	case options.estimated:
+		if !isEmptyQuery(r.Params) {
+			return info, ErrEstimateUnavailable
+		}
+		count, err = r.count(ctx, db, nil, 0, 0)
+		info.TotalEstimated = true
+	case options.limit > 0:
+		count, err = r.count(ctx, db, r.Params, 0, options.limit+1)
+		if count > options.limit {
+			count = options.limit
+			info.TotalCapped = true
+		}
+	default:
+		count, err = r.count(ctx, db, r.Params, 0, 0)
+	}
 
 	if err != nil {
-		return info, err
+		return info, wrapError(err)
 	}
 
 	// Calculate how many pages
-	totalPages := int(math.Ceil(float64(count) / float64(perPage)))
+	totalPages := -1
+	if count >= 0 {
+		totalPages = int(math.Ceil(float64(count) / float64(perPage)))
+	}
 
 	if page < 1 {
 		page = 1
-	} else if page > totalPages {
+	} else if count >= 0 && !info.TotalCapped && page > totalPages {
 		page = totalPages
 	}
 
 	skip := (page - 1) * perPage
+	if skip < 0 {
+		skip = 0
+	}
 
 	r.Query.Skip(skip).Limit(perPage)
 
@@ -103,9 +249,21 @@ func (r *ResultSet) Paginate(perPage, page int) (*PaginationInfo, error) {
 	info.Current = page
 	info.TotalRecords = count
 
-	if info.Current < info.TotalPages {
+	switch {
+	case count < 0 || (info.TotalCapped && page >= totalPages):
+		// Past what was counted, so look at this page and one more document
+		n, err := r.count(ctx, db, r.Params, skip, perPage+1)
+		if err != nil {
+			return info, wrapError(err)
+		}
+		info.HasNextPage = n > perPage
+		info.RecordsOnPage = int(math.Min(float64(n), float64(perPage)))
+
+	case info.Current < info.TotalPages:
 		info.RecordsOnPage = info.PerPage
-	} else {
+		info.HasNextPage = true
+
+	default:
 
 		info.RecordsOnPage = int(math.Mod(float64(count), float64(perPage)))
 
@@ -117,3 +275,55 @@ func (r *ResultSet) Paginate(perPage, page int) (*PaginationInfo, error) {
 
 	return info, nil
 }
+
+// count runs the count command for the result set's collection, with the hint set through Hint.
+// A nil query counts from the collection's metadata
+func (r *ResultSet) count(ctx context.Context, db *mgo.Database, query interface{}, skip, limit int) (int, error) {
+	cmd := bson.D{{Name: "count", Value: r.Collection.Name}}
+
+	if query != nil {
+		cmd = append(cmd, bson.DocElem{Name: "query", Value: query})
+		if len(r.hint) > 0 {
+			cmd = append(cmd, bson.DocElem{Name: "hint", Value: indexKeyDoc(r.hint)})
+		}
+	}
+	if skip > 0 {
+		cmd = append(cmd, bson.DocElem{Name: "skip", Value: skip})
+	}
+	if limit > 0 {
+		cmd = append(cmd, bson.DocElem{Name: "limit", Value: limit})
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		cmd = append(cmd, bson.DocElem{Name: "maxTimeMS", Value: int64(time.Until(deadline) / time.Millisecond)})
+	}
+
+	result := struct{ N int }{}
+	err := db.Run(cmd, &result)
+	return result.N, err
+}
+
+// isEmptyQuery tells if a query matches every document
+func isEmptyQuery(query interface{}) bool {
+	switch q := query.(type) {
+	case nil:
+		return true
+	case bson.M:
+		return len(q) == 0
+	case bson.D:
+		return len(q) == 0
+	}
+	return false
+}
+
+// indexKeyDoc turns keys in the format of mgo's Query.Hint into an index key document
+func indexKeyDoc(keys []string) bson.D {
+	doc := bson.D{}
+	for _, key := range keys {
+		if strings.HasPrefix(key, "-") {
+			doc = append(doc, bson.DocElem{Name: key[1:], Value: -1})
+		} else {
+			doc = append(doc, bson.DocElem{Name: strings.TrimPrefix(key, "+"), Value: 1})
+		}
+	}
+	return doc
+}
@@ -1,6 +1,9 @@
 package bongo
 
 import (
+	"context"
+	"errors"
+
 	. "github.com/smartystreets/goconvey/convey"
 	"gopkg.in/mgo.v2/bson"
 	"testing"
@@ -32,6 +35,19 @@ func TestResultSet(t *testing.T) {
 			So(count, ShouldEqual, 10)
 		})
 
+		Convey("should stop iterating once the context is cancelled", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			rset := collection.FindCtx(ctx, nil)
+			defer rset.Free()
+
+			doc := &noHookDocument{}
+			So(rset.Next(doc), ShouldEqual, true)
+
+			cancel()
+			So(rset.Next(doc), ShouldEqual, false)
+			So(rset.Error, ShouldEqual, context.Canceled)
+		})
+
 		Convey("should let you paginate and get pagination info", func() {
 			rset := collection.Find(nil)
 			defer rset.Free()
@@ -42,6 +58,7 @@ func TestResultSet(t *testing.T) {
 			So(info.Current, ShouldEqual, 1)
 			So(info.PerPage, ShouldEqual, 3)
 			So(info.RecordsOnPage, ShouldEqual, 3)
+			So(info.HasNextPage, ShouldEqual, true)
 
 			rset2 := collection.Find(nil)
 			defer rset2.Free()
@@ -52,6 +69,40 @@ func TestResultSet(t *testing.T) {
 			So(info.Current, ShouldEqual, 4)
 			So(info.PerPage, ShouldEqual, 3)
 			So(info.RecordsOnPage, ShouldEqual, 1)
+			So(info.HasNextPage, ShouldEqual, false)
+		})
+
+		Convey("should let you cap or skip the count", func() {
+			rset := collection.Find(nil)
+			defer rset.Free()
+			info, err := rset.Paginate(3, 1, CountLimit(5))
+			So(err, ShouldEqual, nil)
+			So(info.TotalRecords, ShouldEqual, 5)
+			So(info.TotalCapped, ShouldEqual, true)
+			So(info.HasNextPage, ShouldEqual, true)
+
+			rset2 := collection.Find(nil)
+			defer rset2.Free()
+			info, err = rset2.Paginate(3, 4, CountLimit(5))
+			So(err, ShouldEqual, nil)
+			So(info.Current, ShouldEqual, 4)
+			So(info.RecordsOnPage, ShouldEqual, 1)
+			So(info.HasNextPage, ShouldEqual, false)
+
+			rset3 := collection.Find(nil)
+			defer rset3.Free()
+			info, err = rset3.Paginate(3, 3, SkipCount())
+			So(err, ShouldEqual, nil)
+			So(info.TotalRecords, ShouldEqual, -1)
+			So(info.RecordsOnPage, ShouldEqual, 3)
+			So(info.HasNextPage, ShouldEqual, true)
+
+			rset4 := collection.Find(nil)
+			defer rset4.Free()
+			info, err = rset4.Paginate(3, 1, EstimatedCount())
+			So(err, ShouldEqual, nil)
+			So(info.TotalRecords, ShouldEqual, 10)
+			So(info.TotalEstimated, ShouldEqual, true)
 		})
 
 		Reset(func() {
@@ -115,6 +166,154 @@ func TestResultSet(t *testing.T) {
 			So(info.RecordsOnPage, ShouldEqual, 2)
 		})
 
+		Convey("should keep the sort and projection when paginating", func() {
+			rset := collection.Find(nil).Sort("-name", "_id").Select(bson.M{"name": 1})
+			defer rset.Free()
+			info, err := rset.Paginate(4, 2, EstimatedCount())
+			So(err, ShouldEqual, nil)
+			So(info.TotalEstimated, ShouldEqual, true)
+
+			doc := &noHookDocument{}
+			names := []string{}
+			for rset.Next(doc) {
+				names = append(names, doc.Name)
+				So(doc.CreatedAt.IsZero(), ShouldEqual, true)
+			}
+			So(names, ShouldResemble, []string{"foo", "bar", "bar", "bar"})
+		})
+
+		Convey("should count filtered results with a hint", func() {
+			So(collection.Collection().EnsureIndexKey("name"), ShouldEqual, nil)
+
+			rset := collection.Find(bson.M{"name": "bar"}).Hint("name")
+			defer rset.Free()
+			info, err := rset.Paginate(3, 1)
+			So(err, ShouldEqual, nil)
+			So(info.TotalRecords, ShouldEqual, 5)
+		})
+
+		Convey("should refuse to estimate the count of filtered results", func() {
+			rset := collection.Find(bson.M{"name": "bar"})
+			defer rset.Free()
+			_, err := rset.Paginate(3, 1, EstimatedCount())
+			So(errors.Is(err, ErrEstimateUnavailable), ShouldEqual, true)
+		})
+
+		Reset(func() {
+			conn.Session.DB("bongotest").DropDatabase()
+		})
+	})
+
+	Convey("Cursor pagination", t, func() {
+		for i := 0; i < 7; i++ {
+			doc := &noHookDocument{}
+			doc.Name = string(rune('a' + i%3))
+			collection.Save(doc)
+		}
+
+		names := func(rset *ResultSet) []string {
+			defer rset.Free()
+			found := []string{}
+			doc := &noHookDocument{}
+			for rset.Next(doc) {
+				found = append(found, doc.Name)
+			}
+			return found
+		}
+
+		Convey("should page forward and backward through the sort order", func() {
+			rset := collection.Find(nil)
+			info, err := rset.PaginateAfter("", 3, "-name")
+			So(err, ShouldEqual, nil)
+			So(info.RecordsOnPage, ShouldEqual, 3)
+			So(info.Previous, ShouldEqual, "")
+			So(names(rset), ShouldResemble, []string{"c", "c", "b"})
+
+			rset = collection.Find(nil)
+			info, err = rset.PaginateAfter(info.Next, 3, "-name")
+			So(err, ShouldEqual, nil)
+			So(names(rset), ShouldResemble, []string{"b", "a", "a"})
+
+			rset = collection.Find(nil)
+			last, err := rset.PaginateAfter(info.Next, 3, "-name")
+			So(err, ShouldEqual, nil)
+			So(last.RecordsOnPage, ShouldEqual, 1)
+			So(last.Next, ShouldEqual, "")
+			So(names(rset), ShouldResemble, []string{"a"})
+
+			rset = collection.Find(nil)
+			info, err = rset.PaginateAfter(last.Previous, 3, "-name")
+			So(err, ShouldEqual, nil)
+			So(names(rset), ShouldResemble, []string{"b", "a", "a"})
+
+			rset = collection.Find(nil)
+			info, err = rset.PaginateAfter(info.Previous, 3, "-name")
+			So(err, ShouldEqual, nil)
+			So(info.Previous, ShouldEqual, "")
+			So(info.Next, ShouldNotEqual, "")
+			So(names(rset), ShouldResemble, []string{"c", "c", "b"})
+		})
+
+		Convey("should respect the query", func() {
+			rset := collection.Find(bson.M{"name": "a"})
+			info, err := rset.PaginateAfter("", 2, "name")
+			So(err, ShouldEqual, nil)
+			So(names(rset), ShouldResemble, []string{"a", "a"})
+
+			rset = collection.Find(bson.M{"name": "a"})
+			info, err = rset.PaginateAfter(info.Next, 2, "name")
+			So(err, ShouldEqual, nil)
+			So(info.Next, ShouldEqual, "")
+			So(names(rset), ShouldResemble, []string{"a"})
+		})
+
+		Convey("should reject a cursor for a different query", func() {
+			rset := collection.Find(bson.M{"name": "a"})
+			info, _ := rset.PaginateAfter("", 2, "name")
+			rset.Free()
+
+			rset = collection.Find(bson.M{"name": "b"})
+			defer rset.Free()
+			_, err := rset.PaginateAfter(info.Next, 2, "name")
+			So(err, ShouldEqual, ErrInvalidCursor)
+		})
+
+		Convey("should point back from an empty page", func() {
+			rset := collection.Find(nil)
+			info, err := rset.PaginateAfter("", 5, "name")
+			So(err, ShouldEqual, nil)
+			So(names(rset), ShouldResemble, []string{"a", "a", "a", "b", "b"})
+
+			// The documents past the cursor are gone
+			_, err = collection.Collection().RemoveAll(bson.M{"name": "c"})
+			So(err, ShouldEqual, nil)
+
+			rset = collection.Find(nil)
+			empty, err := rset.PaginateAfter(info.Next, 5, "name")
+			So(err, ShouldEqual, nil)
+			So(empty.RecordsOnPage, ShouldEqual, 0)
+			So(empty.Next, ShouldEqual, "")
+			So(empty.Previous, ShouldNotEqual, "")
+			rset.Free()
+
+			rset = collection.Find(nil)
+			back, err := rset.PaginateAfter(empty.Previous, 5, "name")
+			So(err, ShouldEqual, nil)
+			So(back.Previous, ShouldEqual, "")
+			So(names(rset), ShouldResemble, []string{"a", "a", "a", "b", "b"})
+		})
+
+		Convey("should reject a cursor for a different sort", func() {
+			rset := collection.Find(nil)
+			info, _ := rset.PaginateAfter("", 3, "name")
+			rset.Free()
+
+			rset = collection.Find(nil)
+			defer rset.Free()
+			_, err := rset.PaginateAfter(info.Next, 3, "-name")
+			So(err, ShouldEqual, ErrInvalidCursor)
+		})
+
 		Reset(func() {
 			conn.Session.DB("bongotest").DropDatabase()
 		})
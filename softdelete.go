@@ -0,0 +1,142 @@
+package bongo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// SoftDeletable documents are marked as deleted instead of being removed. The deletion
+// time must be stored in the "deletedAt" field, and omitted (or null) if not deleted
+type SoftDeletable interface {
+	SetDeleted(time.Time)
+	IsDeleted() bool
+}
+
+// Which soft deleted documents a find returns
+const (
+	excludeDeleted = iota
+	includeDeleted = iota
+	onlyDeleted    = iota
+)
+
+type findOptions struct {
+	deleted int
+}
+
+// FindOption changes how a find treats soft deleted documents
+type FindOption func(*findOptions)
+
+// WithDeleted includes soft deleted documents in the results
+func WithDeleted() FindOption {
+	return func(o *findOptions) {
+		o.deleted = includeDeleted
+	}
+}
+
+// OnlyDeleted only returns soft deleted documents
+func OnlyDeleted() FindOption {
+	return func(o *findOptions) {
+		o.deleted = onlyDeleted
+	}
+}
+
+// scopeQuery adds the soft delete condition to a query. Deleted documents are excluded by default
+// if the collection has SoftDelete set
+func (c *Collection) scopeQuery(query interface{}, opts []FindOption) interface{} {
+	options := &findOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	var cond bson.M
+
+	switch options.deleted {
+	case includeDeleted:
+		return query
+	case onlyDeleted:
+		cond = bson.M{"deletedAt": bson.M{"$ne": nil}}
+	default:
+		if !c.SoftDelete {
+			return query
+		}
+		cond = bson.M{"deletedAt": nil}
+	}
+
+	if query == nil {
+		return cond
+	}
+	return bson.M{"$and": []interface{}{query, cond}}
+}
+
+// setDeleted stores the deletion time on a soft deletable document (a zero time restores it)
+func (c *Collection) setDeleted(col *mgo.Collection, doc SoftDeletable, t time.Time) error {
+	id := doc.(Document).GetID()
+	selector := bson.M{"_id": id}
+
+	update := bson.M{}
+	if t.IsZero() {
+		update["$unset"] = bson.M{"deletedAt": ""}
+	} else {
+		update["$set"] = bson.M{"deletedAt": t}
+	}
+
+	versioned, isVersioned := doc.(VersionedDocument)
+	if isVersioned {
+		selector["_v"] = versionSelector(versioned.GetVersion())
+		update["$inc"] = bson.M{"_v": 1}
+	}
+
+	err := col.Update(selector, update)
+	if err == mgo.ErrNotFound {
+		return c.conflictError(col, selector)
+	} else if err != nil {
+		return wrapError(err)
+	}
+
+	doc.SetDeleted(t)
+	if isVersioned {
+		versioned.SetVersion(versioned.GetVersion() + 1)
+	}
+	return nil
+}
+
+// Restore un-deletes a soft deleted document and cascades it to related documents again
+func (c *Collection) Restore(doc Document) error {
+	return c.RestoreCtx(context.Background(), doc)
+}
+
+// RestoreCtx is Restore with a context, see SaveCtx
+func (c *Collection) RestoreCtx(ctx context.Context, doc Document) error {
+	deletable, ok := doc.(SoftDeletable)
+	if !ok {
+		return errors.New("Only SoftDeletable documents can be restored")
+	}
+
+	sess, err := c.sessionCtx(ctx)
+	if err != nil {
+		return err
+	}
+	defer sess.Close()
+
+	err = c.setDeleted(c.CollectionOnSession(sess), deletable, time.Time{})
+	if err != nil {
+		return err
+	}
+
+	return c.cascadeSave(ctx, doc)
+}
+
+// Purge removes a document for good, even if it is SoftDeletable. The delete hooks and cascades
+// run just like they do for Delete
+func (c *Collection) Purge(doc Document) error {
+	return c.PurgeCtx(context.Background(), doc)
+}
+
+// PurgeCtx is Purge with a context, see SaveCtx
+func (c *Collection) PurgeCtx(ctx context.Context, doc Document) error {
+	return c.delete(ctx, doc, true)
+}
@@ -0,0 +1,156 @@
+package bongo
+
+import (
+	"errors"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"gopkg.in/mgo.v2/bson"
+	"testing"
+	"time"
+)
+
+type softDeleteDocument struct {
+	SoftDeleteDocumentBase `bson:",inline"`
+	Name                   string
+	RanAfterDelete         bool `bson:"-"`
+}
+
+func (s *softDeleteDocument) AfterDelete(c *Collection) error {
+	s.RanAfterDelete = true
+	return nil
+}
+
+func TestSoftDelete(t *testing.T) {
+	conn := getConnection()
+	defer conn.Session.Close()
+	conn.EnableSoftDelete("members")
+
+	Convey("Soft delete", t, func() {
+		collection := conn.Collection("members")
+		So(collection.SoftDelete, ShouldEqual, true)
+
+		doc := &softDeleteDocument{}
+		doc.Name = "foo"
+		err := collection.Save(doc)
+		So(err, ShouldEqual, nil)
+
+		err = collection.Delete(doc)
+		So(err, ShouldEqual, nil)
+		So(doc.IsDeleted(), ShouldEqual, true)
+		So(doc.RanAfterDelete, ShouldEqual, true)
+
+		Convey("should keep the document but hide it from finds", func() {
+			count, err := collection.Collection().Count()
+			So(err, ShouldEqual, nil)
+			So(count, ShouldEqual, 1)
+
+			err = collection.FindByID(doc.ID, &softDeleteDocument{})
+			_, ok := err.(*DocumentNotFoundError)
+			So(ok, ShouldEqual, true)
+
+			err = collection.FindOne(bson.M{"name": "foo"}, &softDeleteDocument{})
+			_, ok = err.(*DocumentNotFoundError)
+			So(ok, ShouldEqual, true)
+
+			info, err := collection.Find(nil).Paginate(10, 1)
+			So(err, ShouldEqual, nil)
+			So(info.TotalRecords, ShouldEqual, 0)
+
+			_, err = collection.Find(nil).Paginate(10, 1, EstimatedCount())
+			So(errors.Is(err, ErrEstimateUnavailable), ShouldEqual, true)
+		})
+
+		Convey("should find deleted documents when asked to", func() {
+			found := &softDeleteDocument{}
+			err := collection.FindByID(doc.ID, found, WithDeleted())
+			So(err, ShouldEqual, nil)
+			So(found.IsDeleted(), ShouldEqual, true)
+
+			info, err := collection.Find(bson.M{"name": "foo"}, OnlyDeleted()).Paginate(10, 1)
+			So(err, ShouldEqual, nil)
+			So(info.TotalRecords, ShouldEqual, 1)
+		})
+
+		Convey("should restore a deleted document", func() {
+			err := collection.Restore(doc)
+			So(err, ShouldEqual, nil)
+			So(doc.IsDeleted(), ShouldEqual, false)
+
+			err = collection.FindByID(doc.ID, &softDeleteDocument{})
+			So(err, ShouldEqual, nil)
+		})
+
+		Convey("should purge a document for good and run hooks", func() {
+			doc.RanAfterDelete = false
+			err := collection.Purge(doc)
+			So(err, ShouldEqual, nil)
+			So(doc.RanAfterDelete, ShouldEqual, true)
+
+			count, err := collection.Collection().Count()
+			So(err, ShouldEqual, nil)
+			So(count, ShouldEqual, 0)
+		})
+
+		Reset(func() {
+			conn.Session.DB("bongotest").DropDatabase()
+		})
+	})
+}
+
+func TestSoftDeleteRegistration(t *testing.T) {
+	conn := getConnection()
+	defer conn.Session.Close()
+	conn.EnableSoftDelete("members")
+
+	// Another connection to the same database, without the registration
+	other := getConnection()
+	defer other.Session.Close()
+
+	Convey("Soft delete registration", t, func() {
+		// Inserted directly, so nothing about the documents has been seen before the finds
+		err := conn.Collection("members").Collection().Insert(
+			bson.M{"_id": bson.NewObjectId(), "name": "foo"},
+			bson.M{"_id": bson.NewObjectId(), "name": "bar"},
+			bson.M{"_id": bson.NewObjectId(), "name": "baz", "deletedAt": time.Now()},
+		)
+		So(err, ShouldEqual, nil)
+
+		Convey("should hide deleted documents from Paginate and PaginateAfter before any is loaded", func() {
+			info, err := conn.Collection("members").Find(nil).Paginate(10, 1)
+			So(err, ShouldEqual, nil)
+			So(info.TotalRecords, ShouldEqual, 2)
+
+			cursorInfo, err := conn.Collection("members").Find(nil).PaginateAfter("", 10, "name")
+			So(err, ShouldEqual, nil)
+			So(cursorInfo.RecordsOnPage, ShouldEqual, 2)
+		})
+
+		Convey("should keep what was set directly on the query", func() {
+			rset := conn.Collection("members").Find(nil)
+			rset.Query.Sort("-name").Skip(1).Limit(1)
+
+			docs := []softDeleteDocument{}
+			So(rset.All(&docs), ShouldEqual, nil)
+			So(len(docs), ShouldEqual, 1)
+			So(docs[0].Name, ShouldEqual, "bar")
+		})
+
+		Convey("should only apply to the connection it was registered on", func() {
+			So(other.Collection("members").SoftDelete, ShouldEqual, false)
+
+			docs, err := FindAll[softDeleteDocument](other.Collection("members"), nil)
+			So(err, ShouldEqual, nil)
+			So(len(docs), ShouldEqual, 3)
+		})
+
+		Convey("should return everything with WithDeleted", func() {
+			docs, err := FindAll[*softDeleteDocument](conn.Collection("members"), nil, WithDeleted())
+			So(err, ShouldEqual, nil)
+			So(len(docs), ShouldEqual, 3)
+		})
+
+		Reset(func() {
+			conn.Session.DB("bongotest").DropDatabase()
+		})
+	})
+}
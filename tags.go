@@ -0,0 +1,115 @@
+package bongo
+
+import (
+	"reflect"
+	"strings"
+)
+
+// tagOption is one comma separated entry of a `bongo:"..."` struct tag, e.g. "required" or "max=10"
+type tagOption struct {
+	Key   string
+	Value string
+	// The option couldn't be parsed, e.g. an unquoted regex with a comma
+	Invalid bool
+}
+
+// parseTag splits the bongo struct tag of a field into its options. A regex= pattern containing
+// commas must be quoted in single quotes (doubling any quote in it), e.g. regex='^[0-9]{1,3}$',required.
+// An unquoted regex= takes the rest of the tag, and is marked invalid if that has a comma, so that
+// options after it aren't silently swallowed
+func parseTag(field reflect.StructField) []tagOption {
+	tag := field.Tag.Get("bongo")
+	opts := []tagOption{}
+
+	for len(tag) > 0 {
+		var part string
+
+		if strings.HasPrefix(tag, "regex='") {
+			opt := tagOption{Key: "regex"}
+			opt.Value, tag, opt.Invalid = parseQuoted(tag[len("regex='"):])
+			opts = append(opts, opt)
+			continue
+		}
+
+		if strings.HasPrefix(tag, "regex=") {
+			part, tag = tag, ""
+		} else if i := strings.Index(tag, ","); i >= 0 {
+			part, tag = tag[:i], tag[i+1:]
+		} else {
+			part, tag = tag, ""
+		}
+
+		part = strings.TrimSpace(part)
+		if len(part) == 0 {
+			continue
+		}
+
+		split := strings.SplitN(part, "=", 2)
+		opt := tagOption{Key: split[0]}
+		if len(split) > 1 {
+			opt.Value = split[1]
+		}
+		if opt.Key == "regex" && strings.Contains(opt.Value, ",") {
+			opt.Invalid = true
+		}
+		opts = append(opts, opt)
+	}
+
+	return opts
+}
+
+// parseQuoted reads a single quoted value up to its closing quote, which must be followed by a comma
+// or the end of the tag. It returns the value, the rest of the tag after the comma, and whether the
+// value was malformed (in which case the rest starts after the next comma)
+func parseQuoted(tag string) (string, string, bool) {
+	value := []byte{}
+
+	for i := 0; i < len(tag); i++ {
+		if tag[i] != '\'' {
+			value = append(value, tag[i])
+			continue
+		}
+		if i+1 < len(tag) && tag[i+1] == '\'' {
+			value = append(value, '\'')
+			i++
+			continue
+		}
+
+		rest := tag[i+1:]
+		if len(rest) == 0 {
+			return string(value), "", false
+		}
+		if rest[0] == ',' {
+			return string(value), rest[1:], false
+		}
+		if j := strings.Index(rest, ","); j >= 0 {
+			return string(value), rest[j+1:], true
+		}
+		return string(value), "", true
+	}
+
+	// No closing quote
+	return string(value), "", true
+}
+
+// hasBsonFlag checks the flags after the name in a field's bson tag, e.g. "inline" or "omitempty"
+func hasBsonFlag(field reflect.StructField, flag string) bool {
+	tags := strings.Split(field.Tag.Get("bson"), ",")
+
+	for _, t := range tags[1:] {
+		if t == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// hasTagOption checks if the options contain the key
+func hasTagOption(opts []tagOption, key string) bool {
+	for _, opt := range opts {
+		if opt.Key == key {
+			return true
+		}
+	}
+	return false
+}
@@ -24,3 +24,17 @@ func lowerInitial(str string) string {
 	}
 	return ""
 }
+
+// bsonName returns the key mgo stores the field under, which falls back to the lowercased field
+// name. Anything that builds queries or updates against stored documents must use it
+func bsonName(field reflect.StructField) string {
+	if name := strings.Split(field.Tag.Get("bson"), ",")[0]; len(name) > 0 {
+		return name
+	}
+	return strings.ToLower(field.Name)
+}
+
+// goName returns the struct field name
+func goName(field reflect.StructField) string {
+	return field.Name
+}
@@ -1,14 +1,29 @@
 package bongo
 
 import (
+	"fmt"
 	"gopkg.in/mgo.v2/bson"
 	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
 )
 
 // ValidateRequired ...
+// Slices and maps must not be empty
 func ValidateRequired(val interface{}) bool {
 	valueOf := reflect.ValueOf(val)
-	return valueOf.Interface() != reflect.Zero(valueOf.Type()).Interface()
+
+	switch valueOf.Kind() {
+	case reflect.Invalid:
+		return false
+	case reflect.Slice, reflect.Map:
+		return valueOf.Len() > 0
+	}
+	return !valueOf.IsZero()
 }
 
 // ValidateMongoIDRef ...
@@ -38,3 +53,459 @@ func stringInSlice(a string, list []string) bool {
 func ValidateInclusionIn(value string, options []string) bool {
 	return stringInSlice(value, options)
 }
+
+// ValidateUnique checks that no other document in the collection has the same values for the given
+// bson paths (together, if there are several). The document itself is excluded unless it is new
+func ValidateUnique(doc Document, collection *Collection, fields ...string) bool {
+	data, err := toBsonMap(doc)
+	if err != nil {
+		return false
+	}
+
+	query := bson.M{}
+	for _, f := range fields {
+		// A missing value matches null and missing, just like a unique index
+		query[f], _ = lookupBsonPath(data, strings.Split(f, "."))
+	}
+
+	isNew := !doc.GetID().Valid()
+	if newt, ok := doc.(NewTracker); ok {
+		isNew = newt.IsNew()
+	}
+
+	if !isNew {
+		query["_id"] = bson.M{"$ne": doc.GetID()}
+	}
+
+	count, err := collection.Collection().Find(query).Limit(1).Count()
+
+	if err != nil {
+		return false
+	}
+	return count == 0
+}
+
+// ValidateEmail ...
+func ValidateEmail(value string) bool {
+	return emailRegex.MatchString(value)
+}
+
+var emailRegex = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+
+// Compiled regex= patterns, by pattern
+var tagRegexes sync.Map
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// ValidateStruct runs the validators declared in the bongo struct tags of a document, e.g.
+//
+//	Gender string        `bongo:"required,in=male|female"`
+//	Age    int           `bongo:"min=18,max=130"`
+//	TeamID bson.ObjectId `bongo:"ref=teams"`
+//	Email  string        `bongo:"email"`
+//	Code   string        `bongo:"regex=^[A-Z]{3}$"`
+//	Zip    string        `bongo:"regex='^[0-9]{5}(-[0-9]{4})?$',required"`
+//
+// Options are separated by commas. A regex pattern that contains commas, or that is followed by
+// other options, must be quoted in single quotes (double any quote in the pattern); an unquoted
+// regex takes the rest of the tag and is reported as an invalid_rule if that contains a comma.
+// It recurses into nested structs, pointers and slices. Fields are named by their bson path in the
+// errors. Apart from required and the numeric min/max, rules are skipped for empty values.
+// Collection.PreSave runs this before the Validate hook
+func ValidateStruct(collection *Collection, doc interface{}) []error {
+	errs := validateStruct(collection, reflect.ValueOf(doc), fieldPath{})
+
+	if d, ok := doc.(Document); ok && collection != nil {
+		errs = append(errs, validateUniqueTags(collection, d)...)
+	}
+	return errs
+}
+
+// fieldPath names a field by its bson path and its Go path
+type fieldPath struct {
+	Bson string
+	Go   string
+}
+
+func (f fieldPath) child(bsonName, goName string) fieldPath {
+	return fieldPath{joinPath(f.Bson, bsonName), joinPath(f.Go, goName)}
+}
+
+func joinPath(prefix, name string) string {
+	if len(prefix) == 0 {
+		return name
+	}
+	return prefix + "." + name
+}
+
+func validateStruct(collection *Collection, value reflect.Value, prefix fieldPath) []error {
+	errs := []error{}
+	value = reflect.Indirect(value)
+
+	if value.Kind() != reflect.Struct {
+		return errs
+	}
+
+	t := value.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		// Skip if not exported
+		if len(field.PkgPath) > 0 {
+			continue
+		}
+
+		name := bsonName(field)
+		if name == "-" {
+			continue
+		}
+
+		path := prefix
+		if !hasBsonFlag(field, "inline") {
+			path = prefix.child(name, field.Name)
+		}
+
+		if opts := parseTag(field); len(opts) > 0 {
+			errs = append(errs, validateField(collection, path, value.Field(i), opts)...)
+		}
+		errs = append(errs, validateNested(collection, value.Field(i), path)...)
+	}
+
+	return errs
+}
+
+func validateNested(collection *Collection, value reflect.Value, path fieldPath) []error {
+	switch value.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if value.IsNil() {
+			return nil
+		}
+		return validateNested(collection, value.Elem(), path)
+	case reflect.Struct:
+		if value.Type() == timeType {
+			return nil
+		}
+		return validateStruct(collection, value, path)
+	case reflect.Slice, reflect.Array:
+		errs := []error{}
+		for i := 0; i < value.Len(); i++ {
+			index := strconv.Itoa(i)
+			errs = append(errs, validateNested(collection, value.Index(i), path.child(index, index))...)
+		}
+		return errs
+	}
+	return nil
+}
+
+func validateField(collection *Collection, path fieldPath, value reflect.Value, opts []tagOption) []error {
+	errs := []error{}
+
+	for value.Kind() == reflect.Ptr && !value.IsNil() {
+		value = value.Elem()
+	}
+
+	empty := isEmptyValue(value)
+
+	for _, opt := range opts {
+		var err *FieldError
+
+		switch opt.Key {
+		case "required":
+			if !ValidateRequired(value.Interface()) {
+				err = NewFieldError(path.Bson, path.Go, "required", nil, "%s is required", path.Bson)
+			}
+		case "in":
+			options := strings.Split(opt.Value, "|")
+			if !empty && !ValidateInclusionIn(fmt.Sprint(value.Interface()), options) {
+				err = NewFieldError(path.Bson, path.Go, "in", map[string]interface{}{"options": options},
+					"%s must be one of %s", path.Bson, strings.Join(options, ", "))
+			}
+		case "min", "max":
+			if !empty {
+				err = validateBound(path, value, opt)
+			}
+		case "regex":
+			if opt.Invalid {
+				err = invalidRule(path, opt)
+			} else if !empty && value.Kind() == reflect.String {
+				err = validateRegex(path, value.String(), opt.Value)
+			}
+		case "email":
+			if !empty && value.Kind() == reflect.String && !ValidateEmail(value.String()) {
+				err = NewFieldError(path.Bson, path.Go, "email", nil, "%s is not a valid email address", path.Bson)
+			}
+		case "ref":
+			// In a cascade tag, ref names the field holding the related _id (see Relation)
+			if !empty && collection != nil && !hasTagOption(opts, "cascade") {
+				err = validateRef(path, value, collection.Connection.Collection(opt.Value))
+			}
+		}
+
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errs
+}
+
+// isEmptyValue reports empty strings, slices and maps and nil pointers. Numbers are never empty
+func isEmptyValue(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Invalid:
+		return true
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return value.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return value.IsNil()
+	}
+	return false
+}
+
+// invalidRule reports a malformed tag option, so it shows up instead of silently passing
+func invalidRule(path fieldPath, opt tagOption) *FieldError {
+	return NewFieldError(path.Bson, path.Go, "invalid_rule", map[string]interface{}{"rule": opt.Key, "value": opt.Value},
+		"%s has an invalid %s rule %q", path.Bson, opt.Key, opt.Value)
+}
+
+// validateBound checks min/max against numbers, or the length of strings, slices and maps
+func validateBound(path fieldPath, value reflect.Value, opt tagOption) *FieldError {
+	bound, err := strconv.ParseFloat(opt.Value, 64)
+	if err != nil {
+		return invalidRule(path, opt)
+	}
+
+	var actual float64
+	unit := ""
+
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		actual = float64(value.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		actual = float64(value.Uint())
+	case reflect.Float32, reflect.Float64:
+		actual = value.Float()
+	case reflect.String:
+		actual = float64(utf8.RuneCountInString(value.String()))
+		unit = " characters long"
+	case reflect.Slice, reflect.Map, reflect.Array:
+		actual = float64(value.Len())
+		unit = " items long"
+	default:
+		return nil
+	}
+
+	params := map[string]interface{}{opt.Key: bound}
+
+	if opt.Key == "min" && actual < bound {
+		return NewFieldError(path.Bson, path.Go, "min", params, "%s must be at least %s%s", path.Bson, opt.Value, unit)
+	}
+	if opt.Key == "max" && actual > bound {
+		return NewFieldError(path.Bson, path.Go, "max", params, "%s must be at most %s%s", path.Bson, opt.Value, unit)
+	}
+	return nil
+}
+
+func validateRegex(path fieldPath, value string, pattern string) *FieldError {
+	var re *regexp.Regexp
+
+	if cached, ok := tagRegexes.Load(pattern); ok {
+		re = cached.(*regexp.Regexp)
+	} else {
+		var err error
+		re, err = regexp.Compile(pattern)
+		if err != nil {
+			return invalidRule(path, tagOption{Key: "regex", Value: pattern})
+		}
+		tagRegexes.Store(pattern, re)
+	}
+
+	if !re.MatchString(value) {
+		return NewFieldError(path.Bson, path.Go, "regex", map[string]interface{}{"pattern": pattern},
+			"%s does not match %s", path.Bson, pattern)
+	}
+	return nil
+}
+
+// validateRef checks that an ObjectId (or each one in a slice) references an existing document
+func validateRef(path fieldPath, value reflect.Value, collection *Collection) *FieldError {
+	ids := []bson.ObjectId{}
+
+	if id, ok := value.Interface().(bson.ObjectId); ok {
+		ids = append(ids, id)
+	} else if list, ok := value.Interface().([]bson.ObjectId); ok {
+		ids = list
+	}
+
+	for _, id := range ids {
+		if !ValidateMongoIDRef(id, collection) {
+			return NewFieldError(path.Bson, path.Go, "ref", map[string]interface{}{"collection": collection.Name},
+				"%s does not reference an existing document in %s", path.Bson, collection.Name)
+		}
+	}
+	return nil
+}
+
+// uniqueGroup is a set of fields that must be unique together. Fields tagged with a plain "unique"
+// are a group of their own, fields tagged "unique=name" are grouped by the name
+type uniqueGroup struct {
+	name   string
+	fields []fieldPath
+}
+
+// findUniqueGroups collects the unique tags of a document type. Fields inside slices are skipped,
+// since uniqueness is across documents
+func findUniqueGroups(t reflect.Type, prefix fieldPath, groups []*uniqueGroup, seen map[reflect.Type]bool) []*uniqueGroup {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || t == timeType || seen[t] {
+		return groups
+	}
+
+	// Don't follow recursive types forever
+	seen[t] = true
+	defer delete(seen, t)
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := bsonName(field)
+
+		if len(field.PkgPath) > 0 || name == "-" {
+			continue
+		}
+
+		path := prefix
+		if !hasBsonFlag(field, "inline") {
+			path = prefix.child(name, field.Name)
+		}
+
+		for _, opt := range parseTag(field) {
+			if opt.Key != "unique" {
+				continue
+			}
+
+			var group *uniqueGroup
+			for _, g := range groups {
+				if len(opt.Value) > 0 && g.name == opt.Value {
+					group = g
+				}
+			}
+			if group == nil {
+				group = &uniqueGroup{name: opt.Value}
+				groups = append(groups, group)
+			}
+			group.fields = append(group.fields, path)
+		}
+
+		groups = findUniqueGroups(field.Type, path, groups, seen)
+	}
+	return groups
+}
+
+func validateUniqueTags(collection *Collection, doc Document) []error {
+	errs := []error{}
+	groups := findUniqueGroups(reflect.TypeOf(doc), fieldPath{}, nil, map[reflect.Type]bool{})
+
+	if len(groups) == 0 {
+		return errs
+	}
+
+	data, err := toBsonMap(doc)
+	if err != nil {
+		return append(errs, err)
+	}
+
+	for _, group := range groups {
+		fields := make([]string, len(group.fields))
+		empty := true
+
+		for i, f := range group.fields {
+			fields[i] = f.Bson
+			value, _ := lookupBsonPath(data, strings.Split(f.Bson, "."))
+			if !isEmptyValue(reflect.ValueOf(value)) {
+				empty = false
+			}
+		}
+
+		// Nothing to compare, e.g. an optional field that isn't set
+		if empty {
+			continue
+		}
+
+		if !ValidateUnique(doc, collection, fields...) {
+			errs = append(errs, uniqueError(doc, fields, nil))
+		}
+	}
+	return errs
+}
+
+// uniqueError reports that a set of fields isn't unique. It is named after the first of the fields
+func uniqueError(doc interface{}, fields []string, params map[string]interface{}) *FieldError {
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+	params["fields"] = fields
+
+	path := ""
+	if len(fields) > 0 {
+		path = fields[0]
+	}
+
+	if len(fields) > 1 {
+		return NewFieldError(path, goPath(reflect.TypeOf(doc), path), "unique", params,
+			"%s must be unique together", strings.Join(fields, ", "))
+	}
+	return NewFieldError(path, goPath(reflect.TypeOf(doc), path), "unique", params, "%s must be unique", path)
+}
+
+// goPath maps a bson path to the Go path of the field in a document type, or "" if there isn't one
+func goPath(t reflect.Type, path string) string {
+	if len(path) == 0 {
+		return ""
+	}
+
+	goNames := []string{}
+	for _, name := range strings.Split(path, ".") {
+		for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct {
+			return ""
+		}
+
+		field, ok := findBsonField(t, name)
+		if !ok {
+			return ""
+		}
+		goNames = append(goNames, field.Name)
+		t = field.Type
+	}
+	return strings.Join(goNames, ".")
+}
+
+// findBsonField finds a struct field by its bson name, looking into inline structs
+func findBsonField(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		if len(field.PkgPath) > 0 {
+			continue
+		}
+
+		if hasBsonFlag(field, "inline") {
+			inner := field.Type
+			if inner.Kind() == reflect.Ptr {
+				inner = inner.Elem()
+			}
+			if inner.Kind() == reflect.Struct {
+				if f, ok := findBsonField(inner, name); ok {
+					return f, true
+				}
+			}
+		} else if bsonName(field) == name {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
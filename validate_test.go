@@ -1,11 +1,40 @@
 package bongo
 
 import (
+	"encoding/json"
+	"errors"
+	"reflect"
+
 	. "github.com/smartystreets/goconvey/convey"
+	"gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
 	"testing"
 )
 
+type taggedAddress struct {
+	Street string `bongo:"required"`
+	Zip    string `bson:"zipCode" bongo:"regex=^[0-9]{5}$"`
+}
+
+type taggedDocument struct {
+	DocumentBase `bson:",inline"`
+	Name         string          `bongo:"required,max=5"`
+	Gender       string          `bongo:"in=male|female"`
+	Age          int             `bongo:"min=18,max=130"`
+	Email        string          `bongo:"email"`
+	Tags         []string        `bongo:"max=2"`
+	Address      taggedAddress   `bson:"address"`
+	Others       []taggedAddress `bson:"others"`
+	TeamID       bson.ObjectId   `bson:"teamId,omitempty" bongo:"ref=teams"`
+}
+
+type uniqueDocument struct {
+	DocumentBase `bson:",inline"`
+	Email        string        `bongo:"unique"`
+	TeamID       bson.ObjectId `bson:"teamId" bongo:"unique=number"`
+	Number       int           `bongo:"unique=number"`
+}
+
 func TestValidation(t *testing.T) {
 	Convey("Validation", t, func() {
 		Convey("ValidateRequired()", func() {
@@ -15,6 +44,105 @@ func TestValidation(t *testing.T) {
 			So(ValidateRequired(1), ShouldEqual, true)
 		})
 
+		Convey("ValidateRequired() with slices and maps", func() {
+			So(ValidateRequired([]string{}), ShouldEqual, false)
+			So(ValidateRequired([]string{"foo"}), ShouldEqual, true)
+			So(ValidateRequired(map[string]int{}), ShouldEqual, false)
+			So(ValidateRequired(nil), ShouldEqual, false)
+		})
+
+		Convey("ValidateEmail()", func() {
+			So(ValidateEmail("foo@example.com"), ShouldEqual, true)
+			So(ValidateEmail("foo"), ShouldEqual, false)
+		})
+
+		Convey("ValidateStruct()", func() {
+			doc := &taggedDocument{
+				Name:   "foo",
+				Gender: "male",
+				Age:    30,
+				Address: taggedAddress{
+					Street: "Main St",
+				},
+			}
+
+			So(len(ValidateStruct(nil, doc)), ShouldEqual, 0)
+
+			doc.Name = "foobar"
+			doc.Gender = "other"
+			doc.Age = 0
+			doc.Email = "foo"
+			doc.Tags = []string{"a", "b", "c"}
+			doc.Address = taggedAddress{Zip: "1234"}
+			doc.Others = []taggedAddress{taggedAddress{Street: "Side St"}, taggedAddress{}}
+
+			errs := ValidateStruct(nil, doc)
+			msgs := make([]string, len(errs))
+			for i, err := range errs {
+				msgs[i] = err.Error()
+			}
+
+			So(msgs, ShouldResemble, []string{
+				"name must be at most 5 characters long",
+				"gender must be one of male, female",
+				"age must be at least 18",
+				"email is not a valid email address",
+				"tags must be at most 2 items long",
+				"address.street is required",
+				"address.zipCode does not match ^[0-9]{5}$",
+				"others.1.street is required",
+			})
+		})
+
+		Convey("ValidateStruct() with regex patterns containing commas", func() {
+			type regexDocument struct {
+				Quoted   string `bongo:"regex='^[a-z]{1,3}$',required"`
+				Quote    string `bongo:"regex='^it''s$'"`
+				Unquoted string `bongo:"regex=^[a-z]{1,3}$,required"`
+			}
+
+			field, _ := reflect.TypeOf(regexDocument{}).FieldByName("Quoted")
+			So(parseTag(field), ShouldResemble, []tagOption{
+				{Key: "regex", Value: "^[a-z]{1,3}$"},
+				{Key: "required"},
+			})
+
+			field, _ = reflect.TypeOf(regexDocument{}).FieldByName("Quote")
+			So(parseTag(field), ShouldResemble, []tagOption{{Key: "regex", Value: "^it's$"}})
+
+			errs := ValidateStruct(nil, &regexDocument{Quote: "it's"})
+			So(len(errs), ShouldEqual, 2)
+			So(errs[0].(*FieldError).Code, ShouldEqual, "required")
+			So(errs[1].(*FieldError).Code, ShouldEqual, "invalid_rule")
+		})
+
+		Convey("ValidationError with FieldErrors", func() {
+			doc := &taggedDocument{
+				Name: "foobar",
+				Age:  30,
+			}
+
+			v := &ValidationError{append(ValidateStruct(nil, doc), errors.New("plain error"))}
+
+			fe := v.ForField("address.street")
+			So(len(fe), ShouldEqual, 1)
+			So(fe[0].Field, ShouldEqual, "Address.Street")
+			So(fe[0].Code, ShouldEqual, "required")
+			So(len(v.ForField("name")), ShouldEqual, 1)
+			So(v.ForField("name")[0].Params["max"], ShouldEqual, 5.0)
+			So(len(v.ForField("age")), ShouldEqual, 0)
+
+			So(v.HasCode("max"), ShouldEqual, true)
+			So(v.HasCode("email"), ShouldEqual, false)
+
+			out, err := json.Marshal(v)
+			So(err, ShouldEqual, nil)
+			So(string(out), ShouldEqual, `{"message":"Validation failed","errors":[`+
+				`{"path":"name","field":"Name","code":"max","params":{"max":5},"message":"name must be at most 5 characters long"},`+
+				`{"path":"address.street","field":"Address.Street","code":"required","message":"address.street is required"},`+
+				`{"path":"","field":"","code":"","message":"plain error"}]}`)
+		})
+
 		Convey("ValidateInclusionIn()", func() {
 			So(ValidateInclusionIn("foo", []string{"foo", "bar", "baz"}), ShouldEqual, true)
 			So(ValidateInclusionIn("bing", []string{"foo", "bar", "baz"}), ShouldEqual, false)
@@ -39,5 +167,103 @@ func TestValidation(t *testing.T) {
 			So(ValidateMongoIDRef(bson.NewObjectId(), connection.Collection("other_collection")), ShouldEqual, false)
 
 		})
+
+		Convey("ValidateUnique() and unique tags", func() {
+			connection := getConnection()
+
+			defer func() {
+				connection.Session.DB("bongotest").DropDatabase()
+			}()
+
+			teamID := bson.NewObjectId()
+			doc := &uniqueDocument{
+				Email:  "foo@example.com",
+				TeamID: teamID,
+				Number: 10,
+			}
+			err := connection.Collection("docs").Save(doc)
+			So(err, ShouldEqual, nil)
+
+			// Not counting itself
+			So(ValidateUnique(doc, connection.Collection("docs"), "email"), ShouldEqual, true)
+			err = connection.Collection("docs").Save(doc)
+			So(err, ShouldEqual, nil)
+
+			other := &uniqueDocument{
+				Email:  "foo@example.com",
+				TeamID: teamID,
+				Number: 10,
+			}
+			So(ValidateUnique(other, connection.Collection("docs"), "email"), ShouldEqual, false)
+
+			err = connection.Collection("docs").Save(other)
+			v, ok := err.(*ValidationError)
+			So(ok, ShouldEqual, true)
+			So(len(v.Errors), ShouldEqual, 2)
+			So(v.Errors[0].Error(), ShouldEqual, "email must be unique")
+			So(v.Errors[1].Error(), ShouldEqual, "teamId, number must be unique together")
+
+			other.Email = "bar@example.com"
+			other.Number = 11
+			err = connection.Collection("docs").Save(other)
+			So(err, ShouldEqual, nil)
+		})
+
+		Convey("duplicate key errors as validation errors", func() {
+			connection := getConnection()
+
+			defer func() {
+				connection.Session.DB("bongotest").DropDatabase()
+			}()
+
+			err := connection.Collection("docs").Collection().EnsureIndex(mgo.Index{
+				Key:    []string{"name"},
+				Unique: true,
+			})
+			So(err, ShouldEqual, nil)
+
+			doc := &noHookDocument{Name: "foo"}
+			err = connection.Collection("docs").Save(doc)
+			So(err, ShouldEqual, nil)
+
+			doc2 := &noHookDocument{Name: "foo"}
+			err = connection.Collection("docs").Save(doc2)
+			v, ok := err.(*ValidationError)
+			So(ok, ShouldEqual, true)
+			fe := v.ForField("name")
+			So(len(fe), ShouldEqual, 1)
+			So(fe[0].Code, ShouldEqual, "unique")
+			So(fe[0].Field, ShouldEqual, "Name")
+			So(fe[0].Params["index"], ShouldEqual, "name_1")
+		})
+
+		Convey("tag validation on save", func() {
+			connection := getConnection()
+
+			defer func() {
+				connection.Session.DB("bongotest").DropDatabase()
+			}()
+
+			doc := &taggedDocument{
+				Name:   "foo",
+				Age:    30,
+				TeamID: bson.NewObjectId(),
+			}
+			doc.Address.Street = "Main St"
+
+			err := connection.Collection("docs").Save(doc)
+			v, ok := err.(*ValidationError)
+			So(ok, ShouldEqual, true)
+			So(len(v.Errors), ShouldEqual, 1)
+			So(v.Errors[0].Error(), ShouldEqual, "teamId does not reference an existing document in teams")
+
+			team := &noHookDocument{}
+			err = connection.Collection("teams").Save(team)
+			So(err, ShouldEqual, nil)
+
+			doc.TeamID = team.ID
+			err = connection.Collection("docs").Save(doc)
+			So(err, ShouldEqual, nil)
+		})
 	})
 }